@@ -0,0 +1,166 @@
+package metalcloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	"github.com/ybbus/jsonrpc"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+//apiClient calls the API methods that the Metal Cloud SDK does not wrap, such as subnets, custom ISOs and SSH keys.
+//It uses the same endpoint and credentials as the SDK client it is registered for.
+type apiClient struct {
+	rpcClient jsonrpc.RPCClient
+	userID    int
+}
+
+var (
+	apiClientsLock sync.Mutex
+	apiClients     = map[*mc.Client]*apiClient{}
+)
+
+//newAPIClient returns a client that signs the requests with the API key, or authenticates with an OAuth token
+//when the client credentials are set, the same way as the SDK
+func newAPIClient(apiKey string, endpoint string, loggingEnabled bool, clientID string, clientSecret string, tokenURL string) (*apiClient, error) {
+	userID := 0
+	httpClient := &http.Client{}
+
+	if clientID != "" && clientSecret != "" && tokenURL != "" {
+		n, err := strconv.Atoi(clientID)
+		if err != nil {
+			return nil, err
+		}
+		userID = n
+
+		config := clientcredentials.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			TokenURL:     tokenURL,
+		}
+
+		httpClient = config.Client(context.Background())
+		httpClient.Transport = &apiSignatureRoundTripper{
+			loggingEnabled: loggingEnabled,
+			next:           httpClient.Transport,
+		}
+	} else {
+		if components := strings.Split(apiKey, ":"); len(components) > 1 {
+			n, err := strconv.Atoi(components[0])
+			if err != nil {
+				return nil, err
+			}
+			userID = n
+		}
+
+		httpClient.Transport = &apiSignatureRoundTripper{
+			apiKey:         apiKey,
+			loggingEnabled: loggingEnabled,
+			next:           http.DefaultTransport,
+		}
+	}
+
+	return &apiClient{
+		rpcClient: jsonrpc.NewClientWithOpts(endpoint, &jsonrpc.RPCClientOpts{HTTPClient: httpClient}),
+		userID:    userID,
+	}, nil
+}
+
+//registerAPIClient makes the client available to the resources through the SDK client passed to them as meta
+func registerAPIClient(client *mc.Client, api *apiClient) {
+	apiClientsLock.Lock()
+	defer apiClientsLock.Unlock()
+
+	apiClients[client] = api
+}
+
+//getAPIClient returns the client registered for the SDK client passed as meta
+func getAPIClient(meta interface{}) (*apiClient, error) {
+	apiClientsLock.Lock()
+	defer apiClientsLock.Unlock()
+
+	api, ok := apiClients[meta.(*mc.Client)]
+	if !ok {
+		return nil, fmt.Errorf("the provider is not configured")
+	}
+
+	return api, nil
+}
+
+//call calls an API method and decodes its result into out
+func (c *apiClient) call(out interface{}, method string, params ...interface{}) error {
+	err := c.rpcClient.CallFor(out, method, params...)
+
+	if rpcErr, ok := err.(*jsonrpc.RPCError); ok {
+		return fmt.Errorf("%s", rpcErr.Message)
+	}
+
+	return err
+}
+
+//apiSignatureRoundTripper adds the signature of the request body computed with the API key. Without an API key the
+//requests are authenticated by the next round tripper.
+type apiSignatureRoundTripper struct {
+	apiKey         string
+	loggingEnabled bool
+	next           http.RoundTripper
+}
+
+func (t *apiSignatureRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var message []byte
+	var err error
+
+	if req.Body != nil {
+		message, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if t.loggingEnabled {
+		log.Printf("[DEBUG] %s call to: %s %s", req.Method, req.URL, message)
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewBuffer(message))
+
+	if t.apiKey != "" {
+		h := hmac.New(md5.New, []byte(t.apiKey))
+		h.Write(message)
+
+		signature := hex.EncodeToString(h.Sum(nil))
+
+		if components := strings.Split(t.apiKey, ":"); len(components) > 1 {
+			signature = components[0] + ":" + signature
+		}
+
+		values, err := url.ParseQuery(req.URL.RawQuery)
+		if err != nil {
+			return nil, err
+		}
+
+		values.Add("verify", signature)
+		req.URL.RawQuery = values.Encode()
+	}
+
+	resp, err := t.next.RoundTrip(req)
+
+	if t.loggingEnabled && resp != nil && resp.Body != nil {
+		body, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("[DEBUG] response: %s", body)
+		resp.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	}
+
+	return resp, err
+}
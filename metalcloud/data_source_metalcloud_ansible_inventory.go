@@ -0,0 +1,271 @@
+package metalcloud
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+//DataSourceAnsibleInventory renders an Ansible inventory of the instances of an infrastructure,
+//with a group for each instance array.
+func DataSourceAnsibleInventory() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceAnsibleInventoryRead,
+		Schema: map[string]*schema.Schema{
+			"infrastructure_id": {
+				Type:     schema.TypeInt,
+				Required: true,
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					v := val.(int)
+					if v == 0 {
+						errs = append(errs, fmt.Errorf("%q is required. Provided value: %d", key, v))
+					}
+					return
+				},
+			},
+			"format": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  ANSIBLE_INVENTORY_FORMAT_INI,
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					v := val.(string)
+					if v != ANSIBLE_INVENTORY_FORMAT_INI && v != ANSIBLE_INVENTORY_FORMAT_YAML {
+						errs = append(errs, fmt.Errorf("%q must be one of '%s', '%s'. Provided value: %s", key, ANSIBLE_INVENTORY_FORMAT_INI, ANSIBLE_INVENTORY_FORMAT_YAML, v))
+					}
+					return
+				},
+			},
+			"include_secrets": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"inventory": {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+		},
+	}
+}
+
+type ansibleInventoryHost struct {
+	name string
+	vars map[string]string
+}
+
+type ansibleInventoryGroup struct {
+	name  string
+	vars  map[string]string
+	hosts []ansibleInventoryHost
+}
+
+func dataSourceAnsibleInventoryRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+
+	var diags diag.Diagnostics
+
+	client := meta.(*mc.Client)
+
+	infrastructureID := d.Get("infrastructure_id").(int)
+	includeSecrets := d.Get("include_secrets").(bool)
+
+	instanceArrays, err := client.InstanceArrays(infrastructureID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	groups := []ansibleInventoryGroup{}
+	groupLabels := map[string]string{}
+
+	for _, ia := range *instanceArrays {
+		//labels that only differ by a dash and an underscore would be merged into the same group
+		name := ansibleGroupName(ia.InstanceArrayLabel)
+		if label, ok := groupLabels[name]; ok {
+			return diag.Errorf("instance arrays %s and %s both map to the Ansible group %s. Rename one of them", label, ia.InstanceArrayLabel, name)
+		}
+		groupLabels[name] = ia.InstanceArrayLabel
+
+		retInstances, err := client.InstanceArrayInstances(ia.InstanceArrayID)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		group := ansibleInventoryGroup{
+			name:  name,
+			vars:  flattenAnsibleVars(ia.InstanceArrayCustomVariables),
+			hosts: []ansibleInventoryHost{},
+		}
+
+		for _, instance := range sortInstances(*retInstances) {
+			i, err := client.InstanceGet(instance.InstanceID)
+			if err != nil {
+				return diag.FromErr(err)
+			}
+
+			group.hosts = append(group.hosts, expandAnsibleInventoryHost(*i, includeSecrets))
+		}
+
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(a, b int) bool {
+		return groups[a].name < groups[b].name
+	})
+
+	if d.Get("format").(string) == ANSIBLE_INVENTORY_FORMAT_YAML {
+		d.Set("inventory", renderAnsibleInventoryYAML(groups))
+	} else {
+		d.Set("inventory", renderAnsibleInventoryINI(groups))
+	}
+
+	d.SetId(fmt.Sprintf("%d", infrastructureID))
+
+	return diags
+}
+
+//expandAnsibleInventoryHost returns the host with its custom variables and the connection details
+func expandAnsibleInventoryHost(i mc.Instance, includeSecrets bool) ansibleInventoryHost {
+	vars := flattenAnsibleVars(i.InstanceCustomVariables)
+
+	credentials := i.InstanceCredentials
+
+	if host := instanceHostAddress(i); host != "" {
+		vars["ansible_host"] = host
+	}
+
+	if credentials.SSH != nil {
+		if credentials.SSH.Port != 0 {
+			vars["ansible_port"] = strconv.Itoa(credentials.SSH.Port)
+		}
+
+		if credentials.SSH.Username != "" {
+			vars["ansible_user"] = credentials.SSH.Username
+		}
+
+		if includeSecrets && credentials.SSH.InitialPassword != "" {
+			vars["ansible_password"] = credentials.SSH.InitialPassword
+		}
+	}
+
+	return ansibleInventoryHost{
+		name: i.InstanceLabel,
+		vars: vars,
+	}
+}
+
+func flattenAnsibleVars(customVariables interface{}) map[string]string {
+	vars := make(map[string]string)
+
+	//the reserved variables may hold user data
+	for k, v := range removeReservedCustomVariables(flattenInstanceCustomVariables(customVariables)) {
+		vars[k] = v.(string)
+	}
+
+	return vars
+}
+
+var ansibleGroupNameRegexp = regexp.MustCompile("[^A-Za-z0-9_]")
+
+//ansibleGroupName returns the instance array label as a valid group name, Ansible does not allow dashes in group names
+func ansibleGroupName(label string) string {
+	return ansibleGroupNameRegexp.ReplaceAllString(label, "_")
+}
+
+func sortedAnsibleVarNames(vars map[string]string) []string {
+	keys := []string{}
+
+	for k := range vars {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func renderAnsibleInventoryINI(groups []ansibleInventoryGroup) string {
+	var sb strings.Builder
+
+	for _, g := range groups {
+		fmt.Fprintf(&sb, "[%s]\n", g.name)
+
+		for _, h := range g.hosts {
+			sb.WriteString(h.name)
+			for _, k := range sortedAnsibleVarNames(h.vars) {
+				fmt.Fprintf(&sb, " %s=%s", k, quoteAnsibleINIValue(h.vars[k]))
+			}
+			sb.WriteString("\n")
+		}
+
+		if len(g.vars) > 0 {
+			fmt.Fprintf(&sb, "\n[%s:vars]\n", g.name)
+			for _, k := range sortedAnsibleVarNames(g.vars) {
+				fmt.Fprintf(&sb, "%s=%s\n", k, quoteAnsibleINIValue(g.vars[k]))
+			}
+		}
+
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+//quoteAnsibleINIValue quotes values that would otherwise be split or cut by the INI parser
+func quoteAnsibleINIValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\"'#;=\\") {
+		return strconv.Quote(v)
+	}
+
+	return v
+}
+
+//renderAnsibleInventoryYAML renders the inventory with double quoted scalars so that no value is reinterpreted by YAML
+func renderAnsibleInventoryYAML(groups []ansibleInventoryGroup) string {
+	var sb strings.Builder
+
+	sb.WriteString("all:\n")
+
+	if len(groups) == 0 {
+		return sb.String()
+	}
+
+	sb.WriteString("  children:\n")
+
+	for _, g := range groups {
+		fmt.Fprintf(&sb, "    %s:\n", strconv.Quote(g.name))
+
+		if len(g.hosts) > 0 {
+			sb.WriteString("      hosts:\n")
+			for _, h := range g.hosts {
+				if len(h.vars) == 0 {
+					fmt.Fprintf(&sb, "        %s: {}\n", strconv.Quote(h.name))
+					continue
+				}
+
+				fmt.Fprintf(&sb, "        %s:\n", strconv.Quote(h.name))
+				for _, k := range sortedAnsibleVarNames(h.vars) {
+					fmt.Fprintf(&sb, "          %s: %s\n", strconv.Quote(k), strconv.Quote(h.vars[k]))
+				}
+			}
+		}
+
+		if len(g.vars) > 0 {
+			sb.WriteString("      vars:\n")
+			for _, k := range sortedAnsibleVarNames(g.vars) {
+				fmt.Fprintf(&sb, "        %s: %s\n", strconv.Quote(k), strconv.Quote(g.vars[k]))
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+const ANSIBLE_INVENTORY_FORMAT_INI = "ini"
+const ANSIBLE_INVENTORY_FORMAT_YAML = "yaml"
@@ -84,31 +84,12 @@ func dataSourceInfrastructureOutputRead(ctx context.Context, d *schema.ResourceD
 
 	d.Set("drives", drivesOutput)
 
-	instances := []mc.Instance{}
-
-	instanceArrays, err := client.InstanceArrays(infrastructure_id)
+	instances, err := infrastructureInstances(infrastructure_id, client)
 
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
-	for _, instanceArray := range *instanceArrays {
-		retInstances, err := client.InstanceArrayInstances(instanceArray.InstanceArrayID)
-
-		if err != nil {
-			return diag.FromErr(err)
-		}
-
-		for _, instance := range *retInstances {
-			i, err := client.InstanceGet(instance.InstanceID)
-
-			if err != nil {
-				return diag.FromErr(err)
-			}
-			instances = append(instances, *i)
-		}
-	}
-
 	instancesOutput, err := flattenInstancesInfo(instances)
 
 	if err != nil {
@@ -136,6 +117,52 @@ func dataSourceInfrastructureOutputRead(ctx context.Context, d *schema.ResourceD
 	return diags
 }
 
+//infrastructureInstances returns the instances of all the instance arrays of the infrastructure, including their credentials
+func infrastructureInstances(infrastructureID int, client *mc.Client) ([]mc.Instance, error) {
+	instances := []mc.Instance{}
+
+	instanceArrays, err := client.InstanceArrays(infrastructureID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, instanceArray := range *instanceArrays {
+		retInstances, err := client.InstanceArrayInstances(instanceArray.InstanceArrayID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, instance := range *retInstances {
+			i, err := client.InstanceGet(instance.InstanceID)
+
+			if err != nil {
+				return nil, err
+			}
+			instances = append(instances, *i)
+		}
+	}
+
+	return instances, nil
+}
+
+//instanceHostAddress returns the address on which the instance is reached: its first public IP,
+//its first private IP or its subdomain in this order
+func instanceHostAddress(i mc.Instance) string {
+	credentials := i.InstanceCredentials
+
+	if len(credentials.IPAddressesPublic) > 0 {
+		return credentials.IPAddressesPublic[0].IPHumanReadable
+	}
+
+	if len(credentials.IPAddressesPrivate) > 0 {
+		return credentials.IPAddressesPrivate[0].IPHumanReadable
+	}
+
+	return i.InstanceSubdomain
+}
+
 func flattenDrives(drivesMap *map[string]map[string]mc.Drive) (string, error) {
 	drivesOutput := make(map[string]interface{})
 
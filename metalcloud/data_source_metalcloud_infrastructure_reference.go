@@ -41,9 +41,10 @@ func DataSourceInfrastructureReference() *schema.Resource {
 				ValidateDiagFunc: validateLabel,
 			},
 			"create_if_not_exists": {
-				Type:     schema.TypeBool,
-				Optional: true,
-				Default:  true,
+				Type:       schema.TypeBool,
+				Optional:   true,
+				Default:    true,
+				Deprecated: "Use the metalcloud_infrastructure resource to create infrastructures. Infrastructures created by this data source are not tracked by terraform.",
 			},
 			"infrastructure_id": {
 				Type:     schema.TypeInt,
@@ -0,0 +1,160 @@
+package metalcloud
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+//DataSourceInfrastructures lists the infrastructures visible to the current user, optionally filtered
+func DataSourceInfrastructures() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceInfrastructuresRead,
+		Schema: map[string]*schema.Schema{
+			"datacenter_name": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"label_regex": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					if _, err := regexp.Compile(val.(string)); err != nil {
+						errs = append(errs, fmt.Errorf("%q must be a valid regular expression: %s", key, err))
+					}
+					return
+				},
+			},
+			"service_status": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"deploy_status": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"infrastructures": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"infrastructure_id": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"infrastructure_label": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"datacenter_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"user_id_owner": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"user_email_owner": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"infrastructure_service_status": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"infrastructure_deploy_status": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"infrastructure_created_timestamp": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"infrastructure_updated_timestamp": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceInfrastructuresRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+
+	var diags diag.Diagnostics
+
+	client := meta.(*mc.Client)
+
+	infrastructures, err := client.Infrastructures()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	datacenterName := d.Get("datacenter_name").(string)
+	serviceStatus := d.Get("service_status").(string)
+	deployStatus := d.Get("deploy_status").(string)
+
+	var labelRegex *regexp.Regexp
+	if v := d.Get("label_regex").(string); v != "" {
+		labelRegex = regexp.MustCompile(v)
+	}
+
+	filtered := []mc.Infrastructure{}
+
+	for _, i := range *infrastructures {
+		if datacenterName != "" && i.DatacenterName != datacenterName {
+			continue
+		}
+
+		if labelRegex != nil && !labelRegex.MatchString(i.InfrastructureLabel) {
+			continue
+		}
+
+		if serviceStatus != "" && i.InfrastructureServiceStatus != serviceStatus {
+			continue
+		}
+
+		if deployStatus != "" && i.InfrastructureOperation.InfrastructureDeployStatus != deployStatus {
+			continue
+		}
+
+		filtered = append(filtered, i)
+	}
+
+	sort.Slice(filtered, func(a, b int) bool {
+		return filtered[a].InfrastructureID < filtered[b].InfrastructureID
+	})
+
+	d.Set("infrastructures", flattenInfrastructures(filtered))
+
+	d.SetId(fmt.Sprintf("%d", schema.HashString(fmt.Sprintf("%s/%s/%s/%s", datacenterName, d.Get("label_regex").(string), serviceStatus, deployStatus))))
+
+	return diags
+}
+
+func flattenInfrastructures(infrastructures []mc.Infrastructure) []interface{} {
+	res := []interface{}{}
+
+	for _, i := range infrastructures {
+		res = append(res, map[string]interface{}{
+			"infrastructure_id":                i.InfrastructureID,
+			"infrastructure_label":             i.InfrastructureLabel,
+			"datacenter_name":                  i.DatacenterName,
+			"user_id_owner":                    i.UserIDowner,
+			"user_email_owner":                 i.UserEmailOwner,
+			"infrastructure_service_status":    i.InfrastructureServiceStatus,
+			"infrastructure_deploy_status":     i.InfrastructureOperation.InfrastructureDeployStatus,
+			"infrastructure_created_timestamp": i.InfrastructureCreatedTimestamp,
+			"infrastructure_updated_timestamp": i.InfrastructureUpdatedTimestamp,
+		})
+	}
+
+	return res
+}
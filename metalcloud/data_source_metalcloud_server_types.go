@@ -0,0 +1,384 @@
+package metalcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+//serverTypesSortFields are the attributes of a server type the list can be sorted by
+var serverTypesSortFields = []string{
+	"server_type_name",
+	"server_ram_gbytes",
+	"server_processor_count",
+	"server_processor_core_count",
+	"server_processor_core_mhz",
+	"server_disk_count",
+	"server_disk_size_mbytes",
+	"server_gpu_count",
+	"server_total_count",
+	"server_available_count",
+}
+
+//serverTypeWithGPUs is a server type with the GPU properties that the SDK does not decode. They use the same names
+//as the GPU properties of the servers of the type.
+type serverTypeWithGPUs struct {
+	mc.ServerType
+	ServerGPUCount  int    `json:"server_gpu_count,omitempty"`
+	ServerGPUVendor string `json:"server_gpu_vendor,omitempty"`
+	ServerGPUModel  string `json:"server_gpu_model,omitempty"`
+	availableCount  int
+}
+
+//DataSourceServerTypes lists the server types of a datacenter that match minimum hardware requirements
+func DataSourceServerTypes() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceServerTypesRead,
+		Schema: map[string]*schema.Schema{
+			"datacenter_name": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateDiagFunc: validateLabel,
+			},
+			"min_ram_gbytes": {
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+			"min_processor_count": {
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+			"min_processor_core_count": {
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+			"min_processor_core_mhz": {
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+			"min_disk_count": {
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+			"min_disk_size_mbytes": {
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+			"disk_type": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"min_gpu_count": {
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+			"gpu_model": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"only_available": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"sort_by": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "server_type_name",
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					v := val.(string)
+					for _, f := range serverTypesSortFields {
+						if v == f {
+							return
+						}
+					}
+					errs = append(errs, fmt.Errorf("%q must be one of '%s'. Provided value: %s", key, strings.Join(serverTypesSortFields, "', '"), v))
+					return
+				},
+			},
+			"server_types": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"server_type_id": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"server_type_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"server_type_display_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"server_class": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"server_ram_gbytes": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"server_processor_count": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"server_processor_core_count": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"server_processor_core_mhz": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"server_processor_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"server_disk_count": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"server_disk_size_mbytes": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"server_disk_type": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"server_gpu_count": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"server_gpu_vendor": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"server_gpu_model": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"server_total_count": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"server_available_count": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceServerTypesRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+
+	var diags diag.Diagnostics
+
+	api, err := getAPIClient(meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	datacenterName := d.Get("datacenter_name").(string)
+
+	retServerTypes, err := serverTypesForDatacenter(datacenterName, api)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	serverTypes := []serverTypeWithGPUs{}
+
+	for _, st := range retServerTypes {
+		if serverTypeMatchesRequirements(st, d) {
+			serverTypes = append(serverTypes, st)
+		}
+	}
+
+	availableCounts, err := serverTypesAvailableCount(datacenterName, serverTypes, api)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	filtered := []serverTypeWithGPUs{}
+
+	for _, st := range serverTypes {
+		st.availableCount = availableCounts[st.ServerTypeID]
+
+		if d.Get("only_available").(bool) && st.availableCount == 0 {
+			continue
+		}
+
+		filtered = append(filtered, st)
+	}
+
+	sortServerTypes(filtered, d.Get("sort_by").(string))
+
+	d.Set("server_types", flattenServerTypes(filtered))
+	d.SetId(datacenterName)
+
+	return diags
+}
+
+//serverTypesForDatacenter returns the server types of the datacenter with their GPU properties.
+//The API returns an empty list instead of an empty object when there are no server types.
+func serverTypesForDatacenter(datacenterName string, api *apiClient) ([]serverTypeWithGPUs, error) {
+	var result json.RawMessage
+
+	if err := api.call(&result, "server_types_datacenter", datacenterName); err != nil {
+		return nil, err
+	}
+
+	serverTypes := []serverTypeWithGPUs{}
+
+	if len(result) == 0 || result[0] == '[' {
+		return serverTypes, nil
+	}
+
+	retServerTypes := map[string]serverTypeWithGPUs{}
+
+	if err := json.Unmarshal(result, &retServerTypes); err != nil {
+		return nil, err
+	}
+
+	for _, st := range retServerTypes {
+		serverTypes = append(serverTypes, st)
+	}
+
+	return serverTypes, nil
+}
+
+//serverTypesAvailableCount returns the number of servers of each server type that can be allocated in the datacenter,
+//counted up to SERVER_TYPE_AVAILABLE_COUNT_MAX
+func serverTypesAvailableCount(datacenterName string, serverTypes []serverTypeWithGPUs, api *apiClient) (map[int]int, error) {
+	counts := map[int]int{}
+
+	if len(serverTypes) == 0 {
+		return counts, nil
+	}
+
+	ids := []int{}
+	for _, st := range serverTypes {
+		ids = append(ids, st.ServerTypeID)
+	}
+
+	var result json.RawMessage
+
+	if err := api.call(&result, "server_type_available_server_count_batch", api.userID, datacenterName, ids, SERVER_TYPE_AVAILABLE_COUNT_MAX); err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 || result[0] == '[' {
+		return counts, nil
+	}
+
+	retCounts := map[string]int{}
+
+	if err := json.Unmarshal(result, &retCounts); err != nil {
+		return nil, err
+	}
+
+	for k, v := range retCounts {
+		id, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, err
+		}
+		counts[id] = v
+	}
+
+	return counts, nil
+}
+
+func serverTypeMatchesRequirements(st serverTypeWithGPUs, d *schema.ResourceData) bool {
+	if diskType := d.Get("disk_type").(string); diskType != "" && !strings.EqualFold(st.ServerDiskType, diskType) {
+		return false
+	}
+
+	if gpuModel := d.Get("gpu_model").(string); gpuModel != "" && !strings.Contains(strings.ToLower(st.ServerGPUModel), strings.ToLower(gpuModel)) {
+		return false
+	}
+
+	return st.ServerRAMGbytes >= d.Get("min_ram_gbytes").(int) &&
+		st.ServerProcessorCount >= d.Get("min_processor_count").(int) &&
+		st.ServerProcessorCoreCount >= d.Get("min_processor_core_count").(int) &&
+		st.ServerProcessorCoreMHz >= d.Get("min_processor_core_mhz").(int) &&
+		st.ServerDiskCount >= d.Get("min_disk_count").(int) &&
+		st.ServerDiskSizeMBytes >= d.Get("min_disk_size_mbytes").(int) &&
+		st.ServerGPUCount >= d.Get("min_gpu_count").(int)
+}
+
+//sortServerTypes sorts ascending by the given field, server types with equal values are sorted by name
+func sortServerTypes(serverTypes []serverTypeWithGPUs, sortBy string) {
+	value := func(st serverTypeWithGPUs) int {
+		switch sortBy {
+		case "server_ram_gbytes":
+			return st.ServerRAMGbytes
+		case "server_processor_count":
+			return st.ServerProcessorCount
+		case "server_processor_core_count":
+			return st.ServerProcessorCoreCount
+		case "server_processor_core_mhz":
+			return st.ServerProcessorCoreMHz
+		case "server_disk_count":
+			return st.ServerDiskCount
+		case "server_disk_size_mbytes":
+			return st.ServerDiskSizeMBytes
+		case "server_gpu_count":
+			return st.ServerGPUCount
+		case "server_total_count":
+			return st.ServerCount
+		case "server_available_count":
+			return st.availableCount
+		}
+		return 0
+	}
+
+	sort.SliceStable(serverTypes, func(a, b int) bool {
+		if value(serverTypes[a]) != value(serverTypes[b]) {
+			return value(serverTypes[a]) < value(serverTypes[b])
+		}
+		return serverTypes[a].ServerTypeName < serverTypes[b].ServerTypeName
+	})
+}
+
+func flattenServerTypes(serverTypes []serverTypeWithGPUs) []interface{} {
+	res := []interface{}{}
+
+	for _, st := range serverTypes {
+		res = append(res, map[string]interface{}{
+			"server_type_id":              st.ServerTypeID,
+			"server_type_name":            st.ServerTypeName,
+			"server_type_display_name":    st.ServerTypeDisplayName,
+			"server_class":                st.ServerClass,
+			"server_ram_gbytes":           st.ServerRAMGbytes,
+			"server_processor_count":      st.ServerProcessorCount,
+			"server_processor_core_count": st.ServerProcessorCoreCount,
+			"server_processor_core_mhz":   st.ServerProcessorCoreMHz,
+			"server_processor_name":       st.ServerProcessorName,
+			"server_disk_count":           st.ServerDiskCount,
+			"server_disk_size_mbytes":     st.ServerDiskSizeMBytes,
+			"server_disk_type":            st.ServerDiskType,
+			"server_gpu_count":            st.ServerGPUCount,
+			"server_gpu_vendor":           st.ServerGPUVendor,
+			"server_gpu_model":            st.ServerGPUModel,
+			"server_total_count":          st.ServerCount,
+			"server_available_count":      st.availableCount,
+		})
+	}
+
+	return res
+}
+
+const SERVER_TYPE_AVAILABLE_COUNT_MAX = 1000
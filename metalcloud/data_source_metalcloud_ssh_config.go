@@ -0,0 +1,200 @@
+package metalcloud
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	"golang.org/x/crypto/ssh"
+)
+
+//DataSourceSSHConfig renders an OpenSSH client configuration with a Host entry for each instance of an infrastructure
+func DataSourceSSHConfig() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceSSHConfigRead,
+		Schema: map[string]*schema.Schema{
+			"infrastructure_id": {
+				Type:     schema.TypeInt,
+				Required: true,
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					v := val.(int)
+					if v == 0 {
+						errs = append(errs, fmt.Errorf("%q is required. Provided value: %d", key, v))
+					}
+					return
+				},
+			},
+			"user": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"proxy_jump": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"strict_host_key_checking": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  SSH_STRICT_HOST_KEY_CHECKING_ACCEPT_NEW,
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					v := val.(string)
+					if v != SSH_STRICT_HOST_KEY_CHECKING_YES && v != SSH_STRICT_HOST_KEY_CHECKING_NO && v != SSH_STRICT_HOST_KEY_CHECKING_ACCEPT_NEW && v != SSH_STRICT_HOST_KEY_CHECKING_ASK {
+						errs = append(errs, fmt.Errorf("%q must be one of '%s', '%s', '%s', '%s'. Provided value: %s", key, SSH_STRICT_HOST_KEY_CHECKING_YES, SSH_STRICT_HOST_KEY_CHECKING_NO, SSH_STRICT_HOST_KEY_CHECKING_ACCEPT_NEW, SSH_STRICT_HOST_KEY_CHECKING_ASK, v))
+					}
+					return
+				},
+			},
+			"host_keys": {
+				Type:     schema.TypeMap,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Optional: true,
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					for label, v := range val.(map[string]interface{}) {
+						if _, err := parseSSHPublicKey(v.(string)); err != nil {
+							errs = append(errs, fmt.Errorf("%q: host key of %s: %s", key, label, err))
+						}
+					}
+					return
+				},
+			},
+			"ssh_config": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"known_hosts": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceSSHConfigRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+
+	var diags diag.Diagnostics
+
+	client := meta.(*mc.Client)
+
+	infrastructureID := d.Get("infrastructure_id").(int)
+
+	instances, err := infrastructureInstances(infrastructureID, client)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	sort.Slice(instances, func(a, b int) bool {
+		return instances[a].InstanceLabel < instances[b].InstanceLabel
+	})
+
+	hostKeys := d.Get("host_keys").(map[string]interface{})
+	found := map[string]bool{}
+
+	var sb strings.Builder
+	var kh strings.Builder
+
+	for _, i := range instances {
+		sb.WriteString(renderSSHConfigHost(i, d.Get("user").(string), d.Get("proxy_jump").(string), d.Get("strict_host_key_checking").(string)))
+
+		if hostKey, ok := hostKeys[i.InstanceLabel]; ok {
+			found[i.InstanceLabel] = true
+
+			line, err := renderKnownHostsLine(i, hostKey.(string))
+			if err != nil {
+				return diag.FromErr(err)
+			}
+
+			kh.WriteString(line)
+		}
+	}
+
+	for label := range hostKeys {
+		if !found[label] {
+			return diag.Errorf("host_keys: instance %s is not part of infrastructure %d", label, infrastructureID)
+		}
+	}
+
+	d.Set("ssh_config", sb.String())
+	d.Set("known_hosts", kh.String())
+
+	d.SetId(fmt.Sprintf("%d", infrastructureID))
+
+	return diags
+}
+
+//renderSSHConfigHost renders the Host entry of an instance. The user of the instance credentials is used unless user is set.
+func renderSSHConfigHost(i mc.Instance, user string, proxyJump string, strictHostKeyChecking string) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Host %s\n", i.InstanceLabel)
+
+	if host := instanceHostAddress(i); host != "" {
+		fmt.Fprintf(&sb, "  HostName %s\n", host)
+	}
+
+	ssh := i.InstanceCredentials.SSH
+
+	if user == "" && ssh != nil {
+		user = ssh.Username
+	}
+
+	if user != "" {
+		fmt.Fprintf(&sb, "  User %s\n", user)
+	}
+
+	if ssh != nil && ssh.Port != 0 && ssh.Port != 22 {
+		fmt.Fprintf(&sb, "  Port %d\n", ssh.Port)
+	}
+
+	if proxyJump != "" {
+		fmt.Fprintf(&sb, "  ProxyJump %s\n", proxyJump)
+	}
+
+	fmt.Fprintf(&sb, "  StrictHostKeyChecking %s\n\n", strictHostKeyChecking)
+
+	return sb.String()
+}
+
+//renderKnownHostsLine renders the known_hosts entry of an instance for both its label and its address, the port is
+//added the way OpenSSH expects it when the instance does not use port 22
+func renderKnownHostsLine(i mc.Instance, hostKey string) (string, error) {
+	key, err := parseSSHPublicKey(hostKey)
+	if err != nil {
+		return "", fmt.Errorf("host_keys: host key of %s: %s", i.InstanceLabel, err)
+	}
+
+	hosts := []string{i.InstanceLabel}
+	if host := instanceHostAddress(i); host != "" && host != i.InstanceLabel {
+		hosts = append(hosts, host)
+	}
+
+	if credentials := i.InstanceCredentials.SSH; credentials != nil && credentials.Port != 0 && credentials.Port != 22 {
+		for n, h := range hosts {
+			hosts[n] = fmt.Sprintf("[%s]:%d", h, credentials.Port)
+		}
+	}
+
+	return fmt.Sprintf("%s %s", strings.Join(hosts, ","), ssh.MarshalAuthorizedKey(key)), nil
+}
+
+//parseSSHPublicKey parses a public key in the authorized_keys format, such as the content of a .pub file
+func parseSSHPublicKey(publicKey string) (ssh.PublicKey, error) {
+	key, _, _, rest, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return nil, fmt.Errorf("not a valid SSH public key: %s", err)
+	}
+
+	if len(strings.TrimSpace(string(rest))) > 0 {
+		return nil, fmt.Errorf("only one SSH public key can be set")
+	}
+
+	return key, nil
+}
+
+const SSH_STRICT_HOST_KEY_CHECKING_YES = "yes"
+const SSH_STRICT_HOST_KEY_CHECKING_NO = "no"
+const SSH_STRICT_HOST_KEY_CHECKING_ACCEPT_NEW = "accept-new"
+const SSH_STRICT_HOST_KEY_CHECKING_ASK = "ask"
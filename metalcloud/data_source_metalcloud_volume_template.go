@@ -1,34 +1,96 @@
 package metalcloud
 
 import (
+	"context"
 	"fmt"
 	"log"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
 )
 
 //DataSourceVolumeTemplate provides means to search for volume templates
 func DataSourceVolumeTemplate() *schema.Resource {
+	s := volumeTemplateSchema()
+
+	s["volume_template_label"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Required: true,
+		//this required as the serverside will convert to lowercase and generate a diff
+		//also helpful to prevent other
+		ValidateDiagFunc: validateLabel,
+	}
+
 	return &schema.Resource{
-		Read: dataSourceVolumeTemplateRead,
-		Schema: map[string]*schema.Schema{
-			"volume_template_label": &schema.Schema{
-				Type:     schema.TypeString,
-				Required: true,
-				//this required as the serverside will convert to lowercase and generate a diff
-				//also helpful to prevent other
-				ValidateDiagFunc: validateLabel,
-			},
-			"volume_template_id": &schema.Schema{
-				Type:     schema.TypeInt,
-				Computed: true,
-			},
+		ReadContext: dataSourceVolumeTemplateRead,
+		Schema:      s,
+	}
+}
+
+//volumeTemplateSchema returns the computed attributes of a volume template
+func volumeTemplateSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"volume_template_id": &schema.Schema{
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"volume_template_label": &schema.Schema{
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"volume_template_display_name": &schema.Schema{
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"volume_template_description": &schema.Schema{
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"volume_template_size_mbytes": &schema.Schema{
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"volume_template_version": &schema.Schema{
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"volume_template_boot_methods_supported": &schema.Schema{
+			Type:     schema.TypeList,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+			Computed: true,
+		},
+		"volume_template_boot_type": &schema.Schema{
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"volume_template_deprecation_status": &schema.Schema{
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"volume_template_tags": &schema.Schema{
+			Type:     schema.TypeList,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+			Computed: true,
+		},
+		"operating_system_type": &schema.Schema{
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"operating_system_version": &schema.Schema{
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"operating_system_architecture": &schema.Schema{
+			Type:     schema.TypeString,
+			Computed: true,
 		},
 	}
 }
 
-func dataSourceVolumeTemplateRead(d *schema.ResourceData, meta interface{}) error {
+func dataSourceVolumeTemplateRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
 	client := meta.(*mc.Client)
 
 	volumeTemplateLabel := d.Get("volume_template_label").(string)
@@ -36,33 +98,72 @@ func dataSourceVolumeTemplateRead(d *schema.ResourceData, meta interface{}) erro
 	//in case we need to report it to the user
 	availableVolumeTemplates, err := client.VolumeTemplates()
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 
-	var volumeTemplateID = -1
+	var vt *mc.VolumeTemplate
 	var possibleVolumeTemplateLabels []string
 
 	for _, volumeTemplate := range *availableVolumeTemplates {
 		if volumeTemplate.VolumeTemplateLabel == volumeTemplateLabel {
-			volumeTemplateID = volumeTemplate.VolumeTemplateID
-			if volumeTemplate.VolumeTemplateDeprecationStatus != "not_deprecated" {
-				log.Printf("WARNING: Template %s (%d) is DEPRECATED.", volumeTemplate.VolumeTemplateLabel, volumeTemplate.VolumeTemplateID)
-			}
+			v := volumeTemplate
+			vt = &v
 			break
 		} else {
 			possibleVolumeTemplateLabels = append(possibleVolumeTemplateLabels, volumeTemplate.VolumeTemplateLabel)
 		}
 	}
 
-	if volumeTemplateID == -1 {
-		return fmt.Errorf("Could not find template with volume_template_label=%s. Possible values are: %v",
+	if vt == nil {
+		return diag.Errorf("Could not find template with volume_template_label=%s. Possible values are: %v",
 			volumeTemplateLabel,
 			possibleVolumeTemplateLabels)
 	}
 
-	log.Printf("VolumeTemplateID = %d", volumeTemplateID)
-	d.Set("volume_template_id", volumeTemplateID)
-	d.SetId(fmt.Sprintf("%d", volumeTemplateID))
+	if vt.VolumeTemplateDeprecationStatus != VOLUME_TEMPLATE_NOT_DEPRECATED {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Template %s (%d) is DEPRECATED.", vt.VolumeTemplateLabel, vt.VolumeTemplateID),
+			Detail:   fmt.Sprintf("The deprecation status of the template is %s.", vt.VolumeTemplateDeprecationStatus),
+		})
+	}
+
+	log.Printf("VolumeTemplateID = %d", vt.VolumeTemplateID)
+
+	for k, v := range flattenVolumeTemplate(*vt) {
+		d.Set(k, v)
+	}
+	d.SetId(fmt.Sprintf("%d", vt.VolumeTemplateID))
 
-	return nil
+	return diags
 }
+
+func flattenVolumeTemplate(vt mc.VolumeTemplate) map[string]interface{} {
+	tags := []interface{}{}
+	for _, t := range vt.VolumeTemplateTags {
+		tags = append(tags, t)
+	}
+
+	bootMethods := []interface{}{}
+	for _, m := range volumeTemplateBootMethods(vt) {
+		bootMethods = append(bootMethods, m)
+	}
+
+	return map[string]interface{}{
+		"volume_template_id":                     vt.VolumeTemplateID,
+		"volume_template_label":                  vt.VolumeTemplateLabel,
+		"volume_template_display_name":           vt.VolumeTemplateDisplayName,
+		"volume_template_description":            vt.VolumeTemplateDescription,
+		"volume_template_size_mbytes":            vt.VolumeTemplateSizeMBytes,
+		"volume_template_version":                vt.VolumeTemplateVersion,
+		"volume_template_boot_methods_supported": bootMethods,
+		"volume_template_boot_type":              vt.VolumeTemplateBootType,
+		"volume_template_deprecation_status":     vt.VolumeTemplateDeprecationStatus,
+		"volume_template_tags":                   tags,
+		"operating_system_type":                  vt.VolumeTemplateOperatingSystem.OperatingSystemType,
+		"operating_system_version":               vt.VolumeTemplateOperatingSystem.OperatingSystemVersion,
+		"operating_system_architecture":          vt.VolumeTemplateOperatingSystem.OperatingSystemArchitecture,
+	}
+}
+
+const VOLUME_TEMPLATE_NOT_DEPRECATED = "not_deprecated"
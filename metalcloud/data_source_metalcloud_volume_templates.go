@@ -0,0 +1,243 @@
+package metalcloud
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+//DataSourceVolumeTemplates lists the volume templates available to the current user, optionally filtered
+func DataSourceVolumeTemplates() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceVolumeTemplatesRead,
+		Schema: map[string]*schema.Schema{
+			"label_regex": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					if _, err := regexp.Compile(val.(string)); err != nil {
+						errs = append(errs, fmt.Errorf("%q must be a valid regular expression: %s", key, err))
+					}
+					return
+				},
+			},
+			"operating_system_type": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"operating_system_version": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"operating_system_architecture": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"boot_method": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					v := val.(string)
+					if v != PXE_ISCSI && v != LOCAL_DRIVES {
+						errs = append(errs, fmt.Errorf("%q must be one of '%s', '%s'. Provided value: %s", key, PXE_ISCSI, LOCAL_DRIVES, v))
+					}
+					return
+				},
+			},
+			"boot_type": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"tags": {
+				Type:     schema.TypeSet,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Optional: true,
+			},
+			"include_deprecated": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"most_recent": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"volume_templates": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: volumeTemplateSchema(),
+				},
+			},
+		},
+	}
+}
+
+func dataSourceVolumeTemplatesRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	client := meta.(*mc.Client)
+
+	retVolumeTemplates, err := client.VolumeTemplates()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	volumeTemplates := []mc.VolumeTemplate{}
+
+	for _, vt := range *retVolumeTemplates {
+		if volumeTemplateMatches(vt, d) {
+			volumeTemplates = append(volumeTemplates, vt)
+		}
+	}
+
+	//newest first
+	sort.Slice(volumeTemplates, func(a, b int) bool {
+		return compareVolumeTemplates(volumeTemplates[a], volumeTemplates[b]) > 0
+	})
+
+	if d.Get("most_recent").(bool) && len(volumeTemplates) > 1 {
+		volumeTemplates = volumeTemplates[:1]
+	}
+
+	res := []interface{}{}
+	for _, vt := range volumeTemplates {
+		res = append(res, flattenVolumeTemplate(vt))
+	}
+
+	d.Set("volume_templates", res)
+	d.SetId(fmt.Sprintf("%d", schema.HashString(fmt.Sprintf("%v", volumeTemplatesFilters(d)))))
+
+	return diags
+}
+
+func volumeTemplatesFilters(d *schema.ResourceData) []interface{} {
+	return []interface{}{
+		d.Get("label_regex"),
+		d.Get("operating_system_type"),
+		d.Get("operating_system_version"),
+		d.Get("operating_system_architecture"),
+		d.Get("boot_method"),
+		d.Get("boot_type"),
+		d.Get("tags").(*schema.Set).List(),
+		d.Get("include_deprecated"),
+		d.Get("most_recent"),
+	}
+}
+
+//volumeTemplateMatches returns true if the volume template matches all the filters that are set
+func volumeTemplateMatches(vt mc.VolumeTemplate, d *schema.ResourceData) bool {
+	if !d.Get("include_deprecated").(bool) && vt.VolumeTemplateDeprecationStatus != VOLUME_TEMPLATE_NOT_DEPRECATED {
+		return false
+	}
+
+	if v := d.Get("label_regex").(string); v != "" && !regexp.MustCompile(v).MatchString(vt.VolumeTemplateLabel) {
+		return false
+	}
+
+	operatingSystem := vt.VolumeTemplateOperatingSystem
+
+	if v := d.Get("operating_system_type").(string); v != "" && !strings.EqualFold(operatingSystem.OperatingSystemType, v) {
+		return false
+	}
+
+	if v := d.Get("operating_system_version").(string); v != "" && operatingSystem.OperatingSystemVersion != v {
+		return false
+	}
+
+	if v := d.Get("operating_system_architecture").(string); v != "" && !strings.EqualFold(operatingSystem.OperatingSystemArchitecture, v) {
+		return false
+	}
+
+	if v := d.Get("boot_type").(string); v != "" && !strings.EqualFold(vt.VolumeTemplateBootType, v) {
+		return false
+	}
+
+	if v := d.Get("boot_method").(string); v != "" {
+		supported := false
+		for _, m := range volumeTemplateBootMethods(vt) {
+			if m == v {
+				supported = true
+			}
+		}
+
+		if !supported {
+			return false
+		}
+	}
+
+	for _, tag := range d.Get("tags").(*schema.Set).List() {
+		found := false
+		for _, t := range vt.VolumeTemplateTags {
+			if t == tag.(string) {
+				found = true
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+//volumeTemplateBootMethods returns the boot methods supported by the volume template
+func volumeTemplateBootMethods(vt mc.VolumeTemplate) []string {
+	methods := []string{}
+
+	for _, m := range strings.Split(vt.VolumeTemplateBootMethodsSupported, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			methods = append(methods, m)
+		}
+	}
+
+	return methods
+}
+
+//compareVolumeTemplates orders volume templates by operating system version, then by template version and then by id
+func compareVolumeTemplates(a mc.VolumeTemplate, b mc.VolumeTemplate) int {
+	if c := compareVersions(a.VolumeTemplateOperatingSystem.OperatingSystemVersion, b.VolumeTemplateOperatingSystem.OperatingSystemVersion); c != 0 {
+		return c
+	}
+
+	if c := compareVersions(a.VolumeTemplateVersion, b.VolumeTemplateVersion); c != 0 {
+		return c
+	}
+
+	return a.VolumeTemplateID - b.VolumeTemplateID
+}
+
+var versionSeparatorRegexp = regexp.MustCompile("[^0-9A-Za-z]+")
+
+//compareVersions compares dotted versions such as 7.10 and 7.9 component by component, numerically when both are numbers
+func compareVersions(a string, b string) int {
+	as := versionSeparatorRegexp.Split(a, -1)
+	bs := versionSeparatorRegexp.Split(b, -1)
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+
+		if aErr == nil && bErr == nil {
+			if an != bn {
+				return an - bn
+			}
+			continue
+		}
+
+		if c := strings.Compare(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+
+	return len(as) - len(bs)
+}
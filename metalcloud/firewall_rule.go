@@ -0,0 +1,384 @@
+package metalcloud
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+//firewallRuleRange is a firewall rule with parsed addresses, used to compare rules. A nil address or a zero port means any.
+type firewallRuleRange struct {
+	rule             mc.FirewallRule
+	sourceStart      net.IP
+	sourceEnd        net.IP
+	destinationStart net.IP
+	destinationEnd   net.IP
+	portStart        int
+	portEnd          int
+}
+
+//cidrToRange returns the first and last address of a CIDR block. Blocks with host bits set are rejected rather than
+//normalized as they usually hide a typo in the address or in the prefix length.
+func cidrToRange(cidr string) (string, string, error) {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !ip.Equal(ipNet.IP) {
+		return "", "", fmt.Errorf("%s has host bits set, use %s", cidr, ipNet.String())
+	}
+
+	start := ipNet.IP
+	end := make(net.IP, len(start))
+
+	for i := range start {
+		end[i] = start[i] | ^ipNet.Mask[i]
+	}
+
+	return start.String(), end.String(), nil
+}
+
+//expandFirewallRuleMap expands a firewall_rule block, replacing source_cidr and destination_cidr with address ranges
+func expandFirewallRuleMap(d map[string]interface{}) (mc.FirewallRule, error) {
+	fw := expandFirewallRule(d)
+
+	if cidr, ok := d["source_cidr"].(string); ok && cidr != "" {
+		if fw.FirewallRuleSourceIPAddressRangeStart != "" || fw.FirewallRuleSourceIPAddressRangeEnd != "" {
+			return fw, fmt.Errorf("source_cidr %s cannot be used together with firewall_rule_source_ip_address_range_start or firewall_rule_source_ip_address_range_end", cidr)
+		}
+
+		start, end, err := cidrToRange(cidr)
+		if err != nil {
+			return fw, fmt.Errorf("source_cidr: %s", err)
+		}
+
+		fw.FirewallRuleSourceIPAddressRangeStart = start
+		fw.FirewallRuleSourceIPAddressRangeEnd = end
+	}
+
+	if cidr, ok := d["destination_cidr"].(string); ok && cidr != "" {
+		if fw.FirewallRuleDestinationIPAddressRangeStart != "" || fw.FirewallRuleDestinationIPAddressRangeEnd != "" {
+			return fw, fmt.Errorf("destination_cidr %s cannot be used together with firewall_rule_destination_ip_address_range_start or firewall_rule_destination_ip_address_range_end", cidr)
+		}
+
+		start, end, err := cidrToRange(cidr)
+		if err != nil {
+			return fw, fmt.Errorf("destination_cidr: %s", err)
+		}
+
+		fw.FirewallRuleDestinationIPAddressRangeStart = start
+		fw.FirewallRuleDestinationIPAddressRangeEnd = end
+	}
+
+	return fw, nil
+}
+
+//firewallRuleName returns a short description of the rule to be used in errors
+func firewallRuleName(fw mc.FirewallRule) string {
+	if fw.FirewallRuleDescription != "" {
+		return fmt.Sprintf("%q", fw.FirewallRuleDescription)
+	}
+
+	ports := "any"
+	if fw.FirewallRulePortRangeStart != 0 {
+		ports = formatFirewallRuleRange(fmt.Sprint(fw.FirewallRulePortRangeStart), fmt.Sprint(fw.FirewallRulePortRangeEnd))
+	}
+
+	return fmt.Sprintf("%s %s from %s to %s ports %s",
+		fw.FirewallRuleIPAddressType,
+		fw.FirewallRuleProtocol,
+		formatFirewallRuleRange(fw.FirewallRuleSourceIPAddressRangeStart, fw.FirewallRuleSourceIPAddressRangeEnd),
+		formatFirewallRuleRange(fw.FirewallRuleDestinationIPAddressRangeStart, fw.FirewallRuleDestinationIPAddressRangeEnd),
+		ports)
+}
+
+func formatFirewallRuleRange(start string, end string) string {
+	if start == "" {
+		return "any"
+	}
+
+	if end == "" || end == "0" || end == start {
+		return start
+	}
+
+	return start + "-" + end
+}
+
+//parseFirewallRuleAddressRange parses an address range of a rule. An empty end means the range is a single address.
+func parseFirewallRuleAddressRange(name string, start string, end string, ipAddressType string) (net.IP, net.IP, error) {
+	if start == "" && end == "" {
+		return nil, nil, nil
+	}
+
+	if start == "" {
+		return nil, nil, fmt.Errorf("%s range end %s is set without a range start", name, end)
+	}
+
+	if end == "" {
+		end = start
+	}
+
+	ipStart := net.ParseIP(start)
+	if ipStart == nil {
+		return nil, nil, fmt.Errorf("%s range start %s is not a valid IP address", name, start)
+	}
+
+	ipEnd := net.ParseIP(end)
+	if ipEnd == nil {
+		return nil, nil, fmt.Errorf("%s range end %s is not a valid IP address", name, end)
+	}
+
+	for _, ip := range []net.IP{ipStart, ipEnd} {
+		isIPv4 := ip.To4() != nil
+		if isIPv4 != (ipAddressType == IP_ADDRESS_TYPE_IPV4) {
+			return nil, nil, fmt.Errorf("%s address %s does not match firewall_rule_ip_address_type %s", name, ip, ipAddressType)
+		}
+	}
+
+	if bytes.Compare(ipStart.To16(), ipEnd.To16()) > 0 {
+		return nil, nil, fmt.Errorf("%s range start %s is after range end %s", name, start, end)
+	}
+
+	return ipStart.To16(), ipEnd.To16(), nil
+}
+
+//parseFirewallRule validates a single rule and returns it with parsed addresses and ports
+func parseFirewallRule(fw mc.FirewallRule) (*firewallRuleRange, error) {
+	switch fw.FirewallRuleProtocol {
+	case FIREWALL_PROTOCOL_ALL, FIREWALL_PROTOCOL_ICMP, FIREWALL_PROTOCOL_TCP, FIREWALL_PROTOCOL_UDP:
+	default:
+		return nil, fmt.Errorf("firewall_rule_protocol must be one of %s, %s, %s, %s. Provided value: %s", FIREWALL_PROTOCOL_ALL, FIREWALL_PROTOCOL_ICMP, FIREWALL_PROTOCOL_TCP, FIREWALL_PROTOCOL_UDP, fw.FirewallRuleProtocol)
+	}
+
+	if fw.FirewallRuleIPAddressType != IP_ADDRESS_TYPE_IPV4 && fw.FirewallRuleIPAddressType != IP_ADDRESS_TYPE_IPV6 {
+		return nil, fmt.Errorf("firewall_rule_ip_address_type must be one of %s, %s. Provided value: %s", IP_ADDRESS_TYPE_IPV4, IP_ADDRESS_TYPE_IPV6, fw.FirewallRuleIPAddressType)
+	}
+
+	r := firewallRuleRange{
+		rule:      fw,
+		portStart: fw.FirewallRulePortRangeStart,
+		portEnd:   fw.FirewallRulePortRangeEnd,
+	}
+
+	if r.portStart == 0 && r.portEnd != 0 {
+		return nil, fmt.Errorf("firewall_rule_port_range_end %d is set without firewall_rule_port_range_start", r.portEnd)
+	}
+
+	if r.portEnd == 0 {
+		r.portEnd = r.portStart
+	}
+
+	if r.portStart < 0 || r.portEnd > 65535 {
+		return nil, fmt.Errorf("ports must be between 1 and 65535, got %d-%d", r.portStart, r.portEnd)
+	}
+
+	if r.portStart > r.portEnd {
+		return nil, fmt.Errorf("firewall_rule_port_range_start %d is after firewall_rule_port_range_end %d", r.portStart, r.portEnd)
+	}
+
+	var err error
+
+	r.sourceStart, r.sourceEnd, err = parseFirewallRuleAddressRange("source", fw.FirewallRuleSourceIPAddressRangeStart, fw.FirewallRuleSourceIPAddressRangeEnd, fw.FirewallRuleIPAddressType)
+	if err != nil {
+		return nil, err
+	}
+
+	r.destinationStart, r.destinationEnd, err = parseFirewallRuleAddressRange("destination", fw.FirewallRuleDestinationIPAddressRangeStart, fw.FirewallRuleDestinationIPAddressRangeEnd, fw.FirewallRuleIPAddressType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+func addressRangeContains(aStart, aEnd, bStart, bEnd net.IP) bool {
+	if aStart == nil {
+		return true
+	}
+
+	if bStart == nil {
+		return false
+	}
+
+	return bytes.Compare(aStart, bStart) <= 0 && bytes.Compare(bEnd, aEnd) <= 0
+}
+
+//contains returns true if every packet matched by b is also matched by a
+func (a firewallRuleRange) contains(b firewallRuleRange) bool {
+	if a.rule.FirewallRuleIPAddressType != b.rule.FirewallRuleIPAddressType {
+		return false
+	}
+
+	if a.rule.FirewallRuleProtocol != FIREWALL_PROTOCOL_ALL && a.rule.FirewallRuleProtocol != b.rule.FirewallRuleProtocol {
+		return false
+	}
+
+	if a.portStart != 0 && (b.portStart == 0 || b.portStart < a.portStart || a.portEnd < b.portEnd) {
+		return false
+	}
+
+	return addressRangeContains(a.sourceStart, a.sourceEnd, b.sourceStart, b.sourceEnd) &&
+		addressRangeContains(a.destinationStart, a.destinationEnd, b.destinationStart, b.destinationEnd)
+}
+
+//validateFirewallRules checks each rule and returns warnings for ports set on protocols without ports and for enabled
+//rules that are duplicates of, or shadowed by, another enabled rule. These rules are accepted by the server.
+func validateFirewallRules(rules []mc.FirewallRule) ([]string, error) {
+	warnings := []string{}
+	ranges := []firewallRuleRange{}
+
+	for _, fw := range rules {
+		r, err := parseFirewallRule(fw)
+		if err != nil {
+			return nil, fmt.Errorf("firewall_rule %s: %s", firewallRuleName(fw), err)
+		}
+
+		if r.portStart != 0 && fw.FirewallRuleProtocol != FIREWALL_PROTOCOL_TCP && fw.FirewallRuleProtocol != FIREWALL_PROTOCOL_UDP {
+			warnings = append(warnings, fmt.Sprintf("firewall_rule %s sets ports but ports are only used by the %s and %s protocols", firewallRuleName(fw), FIREWALL_PROTOCOL_TCP, FIREWALL_PROTOCOL_UDP))
+		}
+
+		if fw.FirewallRuleEnabled {
+			ranges = append(ranges, *r)
+		}
+	}
+
+	for i := range ranges {
+		for j := range ranges {
+			if i == j || !ranges[i].contains(ranges[j]) {
+				continue
+			}
+
+			if ranges[j].contains(ranges[i]) {
+				if i < j {
+					warnings = append(warnings, fmt.Sprintf("firewall_rule %s is a duplicate of firewall_rule %s", firewallRuleName(ranges[j].rule), firewallRuleName(ranges[i].rule)))
+				}
+				continue
+			}
+
+			warnings = append(warnings, fmt.Sprintf("firewall_rule %s is shadowed by firewall_rule %s which already allows all of its traffic", firewallRuleName(ranges[j].rule), firewallRuleName(ranges[i].rule)))
+		}
+	}
+
+	return warnings, nil
+}
+
+//checkFirewallRuleWarnings returns the warnings of validateFirewallRules as a plan error unless the redundant rules are
+//allowed, in which case they are only logged as the SDK does not allow warnings on plan
+func checkFirewallRuleWarnings(warnings []string, allowRedundant bool) error {
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	if !allowRedundant {
+		return fmt.Errorf("%s. Set allow_redundant_firewall_rules to true to accept these rules", strings.Join(warnings, "; "))
+	}
+
+	for _, w := range warnings {
+		log.Printf("[WARN] %s", w)
+	}
+
+	return nil
+}
+
+//firewallRuleDiagnostics returns the warnings of validateFirewallRules as diagnostics to be returned on apply
+func firewallRuleDiagnostics(rules []mc.FirewallRule) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	warnings, _ := validateFirewallRules(rules)
+
+	for _, w := range warnings {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  w,
+		})
+	}
+
+	return diags
+}
+
+//uniqueFirewallRules removes identical rules, such as the same rule being part of multiple firewall rule sets
+func uniqueFirewallRules(rules []mc.FirewallRule) []mc.FirewallRule {
+	unique := []mc.FirewallRule{}
+
+	for _, fw := range rules {
+		found := false
+		for _, u := range unique {
+			if u == fw {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			unique = append(unique, fw)
+		}
+	}
+
+	return unique
+}
+
+//excludeFirewallRules removes one copy of each of the excluded rules. A rule that is both configured in firewall_rule
+//and part of a firewall rule set is sent twice so the configured copy is kept.
+func excludeFirewallRules(rules []mc.FirewallRule, excluded []mc.FirewallRule) []mc.FirewallRule {
+	remaining := []mc.FirewallRule{}
+	used := make([]bool, len(excluded))
+
+	for _, fw := range rules {
+		found := false
+		for i, e := range excluded {
+			if !used[i] && e == fw {
+				used[i] = true
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			remaining = append(remaining, fw)
+		}
+	}
+
+	return remaining
+}
+
+//flattenFirewallRules returns the rules as firewall_rule blocks. Rules matching a configured block are returned as configured
+//so that blocks using source_cidr or destination_cidr do not show a difference.
+func flattenFirewallRules(rules []mc.FirewallRule, configured []interface{}) []interface{} {
+	fwRules := []interface{}{}
+
+	for _, fw := range rules {
+		var rule map[string]interface{}
+
+		for _, cIntf := range configured {
+			c := cIntf.(map[string]interface{})
+
+			if expanded, err := expandFirewallRuleMap(c); err == nil && expanded == fw {
+				rule = c
+				break
+			}
+		}
+
+		if rule == nil {
+			rule = flattenFirewallRule(fw)
+		}
+
+		fwRules = append(fwRules, rule)
+	}
+
+	return fwRules
+}
+
+const (
+	FIREWALL_PROTOCOL_ALL  = "all"
+	FIREWALL_PROTOCOL_ICMP = "icmp"
+	FIREWALL_PROTOCOL_TCP  = "tcp"
+	FIREWALL_PROTOCOL_UDP  = "udp"
+	IP_ADDRESS_TYPE_IPV4   = "ipv4"
+	IP_ADDRESS_TYPE_IPV6   = "ipv6"
+)
@@ -0,0 +1,149 @@
+package metalcloud
+
+import (
+	"net"
+	"testing"
+
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+func TestCidrToRange(t *testing.T) {
+	cases := []struct {
+		cidr  string
+		start string
+		end   string
+		err   bool
+	}{
+		{cidr: "10.0.0.0/24", start: "10.0.0.0", end: "10.0.0.255"},
+		{cidr: "10.0.0.1/32", start: "10.0.0.1", end: "10.0.0.1"},
+		{cidr: "0.0.0.0/0", start: "0.0.0.0", end: "255.255.255.255"},
+		{cidr: "84.84.12.0/22", start: "84.84.12.0", end: "84.84.15.255"},
+		{cidr: "2001:db8::/64", start: "2001:db8::", end: "2001:db8::ffff:ffff:ffff:ffff"},
+		{cidr: "2001:db8::1/128", start: "2001:db8::1", end: "2001:db8::1"},
+		{cidr: "84.84.12.5/24", err: true},
+		{cidr: "2001:db8::1/64", err: true},
+		{cidr: "10.0.0.0", err: true},
+		{cidr: "10.0.0.0/33", err: true},
+		{cidr: "", err: true},
+	}
+
+	for _, c := range cases {
+		start, end, err := cidrToRange(c.cidr)
+
+		if c.err {
+			if err == nil {
+				t.Errorf("cidrToRange(%q) = %s, %s, expected an error", c.cidr, start, end)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("cidrToRange(%q) returned an error: %s", c.cidr, err)
+			continue
+		}
+
+		if start != c.start || end != c.end {
+			t.Errorf("cidrToRange(%q) = %s, %s, expected %s, %s", c.cidr, start, end, c.start, c.end)
+		}
+	}
+}
+
+func TestAddressRangeContains(t *testing.T) {
+	ip := func(s string) net.IP {
+		if s == "" {
+			return nil
+		}
+		return net.ParseIP(s).To16()
+	}
+
+	cases := []struct {
+		name     string
+		aStart   string
+		aEnd     string
+		bStart   string
+		bEnd     string
+		expected bool
+	}{
+		{name: "any contains any", expected: true},
+		{name: "any contains a range", bStart: "10.0.0.1", bEnd: "10.0.0.5", expected: true},
+		{name: "a range does not contain any", aStart: "10.0.0.0", aEnd: "10.0.0.255", expected: false},
+		{name: "same range", aStart: "10.0.0.0", aEnd: "10.0.0.255", bStart: "10.0.0.0", bEnd: "10.0.0.255", expected: true},
+		{name: "inner range", aStart: "10.0.0.0", aEnd: "10.0.0.255", bStart: "10.0.0.10", bEnd: "10.0.0.20", expected: true},
+		{name: "single address at the end", aStart: "10.0.0.0", aEnd: "10.0.0.255", bStart: "10.0.0.255", bEnd: "10.0.0.255", expected: true},
+		{name: "overlapping start", aStart: "10.0.0.10", aEnd: "10.0.0.20", bStart: "10.0.0.5", bEnd: "10.0.0.15", expected: false},
+		{name: "overlapping end", aStart: "10.0.0.10", aEnd: "10.0.0.20", bStart: "10.0.0.15", bEnd: "10.0.0.25", expected: false},
+		{name: "outer range", aStart: "10.0.0.10", aEnd: "10.0.0.20", bStart: "10.0.0.0", bEnd: "10.0.0.255", expected: false},
+		{name: "disjoint", aStart: "10.0.0.0", aEnd: "10.0.0.255", bStart: "10.0.1.0", bEnd: "10.0.1.255", expected: false},
+		{name: "ipv6 inner range", aStart: "2001:db8::", aEnd: "2001:db8::ffff", bStart: "2001:db8::10", bEnd: "2001:db8::20", expected: true},
+		{name: "ipv6 disjoint", aStart: "2001:db8::", aEnd: "2001:db8::ffff", bStart: "2001:db9::", bEnd: "2001:db9::1", expected: false},
+	}
+
+	for _, c := range cases {
+		actual := addressRangeContains(ip(c.aStart), ip(c.aEnd), ip(c.bStart), ip(c.bEnd))
+		if actual != c.expected {
+			t.Errorf("%s: addressRangeContains(%s-%s, %s-%s) = %t, expected %t", c.name, c.aStart, c.aEnd, c.bStart, c.bEnd, actual, c.expected)
+		}
+	}
+}
+
+func TestFirewallRuleRangeContains(t *testing.T) {
+	rule := func(protocol string, portStart int, portEnd int, source string) mc.FirewallRule {
+		fw := mc.FirewallRule{
+			FirewallRuleProtocol:       protocol,
+			FirewallRuleIPAddressType:  IP_ADDRESS_TYPE_IPV4,
+			FirewallRulePortRangeStart: portStart,
+			FirewallRulePortRangeEnd:   portEnd,
+			FirewallRuleEnabled:        true,
+		}
+
+		if source != "" {
+			start, end, err := cidrToRange(source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fw.FirewallRuleSourceIPAddressRangeStart = start
+			fw.FirewallRuleSourceIPAddressRangeEnd = end
+		}
+
+		return fw
+	}
+
+	ipv6 := rule(FIREWALL_PROTOCOL_TCP, 22, 22, "")
+	ipv6.FirewallRuleIPAddressType = IP_ADDRESS_TYPE_IPV6
+
+	cases := []struct {
+		name     string
+		a        mc.FirewallRule
+		b        mc.FirewallRule
+		expected bool
+	}{
+		{name: "same rule", a: rule(FIREWALL_PROTOCOL_TCP, 22, 22, "10.0.0.0/24"), b: rule(FIREWALL_PROTOCOL_TCP, 22, 22, "10.0.0.0/24"), expected: true},
+		{name: "all protocols contains tcp", a: rule(FIREWALL_PROTOCOL_ALL, 0, 0, "10.0.0.0/24"), b: rule(FIREWALL_PROTOCOL_TCP, 22, 22, "10.0.0.1/32"), expected: true},
+		{name: "tcp does not contain all protocols", a: rule(FIREWALL_PROTOCOL_TCP, 0, 0, ""), b: rule(FIREWALL_PROTOCOL_ALL, 0, 0, ""), expected: false},
+		{name: "tcp does not contain udp", a: rule(FIREWALL_PROTOCOL_TCP, 0, 0, ""), b: rule(FIREWALL_PROTOCOL_UDP, 53, 53, ""), expected: false},
+		{name: "any port contains a port", a: rule(FIREWALL_PROTOCOL_TCP, 0, 0, ""), b: rule(FIREWALL_PROTOCOL_TCP, 443, 443, ""), expected: true},
+		{name: "a port does not contain any port", a: rule(FIREWALL_PROTOCOL_TCP, 443, 443, ""), b: rule(FIREWALL_PROTOCOL_TCP, 0, 0, ""), expected: false},
+		{name: "port range contains a port", a: rule(FIREWALL_PROTOCOL_TCP, 8000, 8100, ""), b: rule(FIREWALL_PROTOCOL_TCP, 8080, 8080, ""), expected: true},
+		{name: "overlapping port ranges", a: rule(FIREWALL_PROTOCOL_TCP, 8000, 8100, ""), b: rule(FIREWALL_PROTOCOL_TCP, 8050, 8150, ""), expected: false},
+		{name: "wider source", a: rule(FIREWALL_PROTOCOL_TCP, 22, 22, "10.0.0.0/16"), b: rule(FIREWALL_PROTOCOL_TCP, 22, 22, "10.0.5.0/24"), expected: true},
+		{name: "narrower source", a: rule(FIREWALL_PROTOCOL_TCP, 22, 22, "10.0.5.0/24"), b: rule(FIREWALL_PROTOCOL_TCP, 22, 22, "10.0.0.0/16"), expected: false},
+		{name: "any source contains a source", a: rule(FIREWALL_PROTOCOL_TCP, 22, 22, ""), b: rule(FIREWALL_PROTOCOL_TCP, 22, 22, "10.0.0.1/32"), expected: true},
+		{name: "different address types", a: rule(FIREWALL_PROTOCOL_TCP, 22, 22, ""), b: ipv6, expected: false},
+	}
+
+	for _, c := range cases {
+		a, err := parseFirewallRule(c.a)
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+
+		b, err := parseFirewallRule(c.b)
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+
+		if actual := a.contains(*b); actual != c.expected {
+			t.Errorf("%s: %s contains %s = %t, expected %t", c.name, firewallRuleName(c.a), firewallRuleName(c.b), actual, c.expected)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package metalcloud
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+//Instances are booted from a custom ISO through the out of band interface of their servers, which mounts the ISO as
+//virtual media and reboots the server. The API only boots servers that are already allocated, so the instances are
+//booted by the apply following the deploy that allocated their servers. custom_iso_booted_instances records the
+//instances booted from custom_iso_id as the API does not report it.
+
+//diffInstanceArrayCustomISO shows in custom_iso_booted_instances the deployed instances that the apply will boot from
+//custom_iso_id, all of them when the ISO changes and the newly deployed ones otherwise.
+func diffInstanceArrayCustomISO(d *schema.ResourceDiff) error {
+	if d.Id() == "" {
+		return nil
+	}
+
+	if !d.NewValueKnown("custom_iso_id") {
+		return d.SetNewComputed("custom_iso_booted_instances")
+	}
+
+	oList, _ := d.GetChange("custom_iso_booted_instances")
+	booted := expandStringList(oList.([]interface{}))
+
+	labels := []string{}
+
+	if d.Get("custom_iso_id").(int) != 0 {
+		oInstances, _ := d.GetChange("instances")
+
+		if !d.HasChange("custom_iso_id") {
+			labels = append(labels, booted...)
+		}
+
+		labels = mergeLabels(labels, deployedInstanceLabels(oInstances.([]interface{})))
+	}
+
+	if reflect.DeepEqual(labels, booted) {
+		return nil
+	}
+
+	if toBoot := customISOInstancesToBoot(booted, labels, d.HasChange("custom_iso_id")); len(toBoot) > 0 {
+		log.Printf("[WARN] %s", customISOBootMessage(d.Get("instance_array_label").(string), toBoot))
+	}
+
+	return d.SetNew("custom_iso_booted_instances", labels)
+}
+
+//deployedInstanceLabels returns the labels of the instances of the state that have a server
+func deployedInstanceLabels(instances []interface{}) []string {
+	labels := []string{}
+
+	for _, i := range instances {
+		instance := i.(map[string]interface{})
+
+		if instance["server_id"].(int) != 0 {
+			labels = append(labels, instance["instance_label"].(string))
+		}
+	}
+
+	return labels
+}
+
+//mergeLabels returns the sorted labels of both lists, without duplicates
+func mergeLabels(a []string, b []string) []string {
+	seen := map[string]bool{}
+	res := []string{}
+
+	for _, l := range append(append([]string{}, a...), b...) {
+		if !seen[l] {
+			seen[l] = true
+			res = append(res, l)
+		}
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+//customISOInstancesToBoot returns the labels of the instances that are not booted from the custom ISO yet
+func customISOInstancesToBoot(booted []string, labels []string, isoChanged bool) []string {
+	if isoChanged {
+		return labels
+	}
+
+	alreadyBooted := map[string]bool{}
+	for _, l := range booted {
+		alreadyBooted[l] = true
+	}
+
+	res := []string{}
+	for _, l := range labels {
+		if !alreadyBooted[l] {
+			res = append(res, l)
+		}
+	}
+
+	return res
+}
+
+//bootInstancesCustomISO boots the instances planned in custom_iso_booted_instances from the custom ISO. The instances
+//booted before an error are kept in custom_iso_booted_instances so they are not rebooted by the next apply.
+func bootInstancesCustomISO(d *schema.ResourceData, instanceArrayID int, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if !d.HasChange("custom_iso_booted_instances") && !d.HasChange("custom_iso_id") {
+		return diags
+	}
+
+	customISOID := d.Get("custom_iso_id").(int)
+
+	oList, nList := d.GetChange("custom_iso_booted_instances")
+	previous := expandStringList(oList.([]interface{}))
+	labels := expandStringList(nList.([]interface{}))
+
+	isoChanged := d.HasChange("custom_iso_id")
+	if isoChanged {
+		previous = []string{}
+	}
+
+	toBoot := customISOInstancesToBoot(previous, labels, isoChanged)
+
+	if customISOID == 0 || len(toBoot) == 0 {
+		return diags
+	}
+
+	instanceList, err := meta.(*mc.Client).InstanceArrayInstances(instanceArrayID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	instances := map[string]mc.Instance{}
+	for _, i := range *instanceList {
+		instances[i.InstanceLabel] = i
+	}
+
+	booted := previous
+
+	for _, label := range toBoot {
+		i, ok := instances[label]
+		if !ok || i.ServerID == 0 {
+			continue
+		}
+
+		if err := bootCustomISO(customISOID, i.ServerID, meta); err != nil {
+			d.Set("custom_iso_booted_instances", mergeLabels(booted, nil))
+			return diag.Errorf("could not boot instance %s from custom ISO %d: %s", label, customISOID, err)
+		}
+
+		booted = append(booted, label)
+	}
+
+	d.Set("custom_iso_booted_instances", mergeLabels(booted, nil))
+
+	diags = append(diags, diag.Diagnostic{
+		Severity: diag.Warning,
+		Summary:  customISOBootMessage(d.Get("instance_array_label").(string), toBoot),
+	})
+
+	return diags
+}
+
+//flattenCustomISOBootedInstances keeps the booted instances that still have a server
+func flattenCustomISOBootedInstances(booted []interface{}, instances []mc.Instance) []string {
+	deployed := map[string]bool{}
+	for _, i := range instances {
+		if i.ServerID != 0 {
+			deployed[i.InstanceLabel] = true
+		}
+	}
+
+	res := []string{}
+	for _, l := range expandStringList(booted) {
+		if deployed[l] {
+			res = append(res, l)
+		}
+	}
+
+	return res
+}
+
+//checkCustomISO checks that the custom ISO exists
+func checkCustomISO(customISOID int, meta interface{}) error {
+	api, err := getAPIClient(meta)
+	if err != nil {
+		return err
+	}
+
+	var iso customISO
+
+	if err := api.call(&iso, "custom_iso_get", customISOID); err != nil {
+		return bootError{"custom_iso_id", fmt.Sprintf("could not retrieve custom ISO %d: %s", customISOID, err)}
+	}
+
+	return nil
+}
+
+//bootCustomISO boots the server from the custom ISO through its out of band interface. The server is rebooted.
+func bootCustomISO(customISOID int, serverID int, meta interface{}) error {
+	api, err := getAPIClient(meta)
+	if err != nil {
+		return err
+	}
+
+	var result interface{}
+
+	return api.call(&result, "custom_iso_boot_into_server", customISOID, serverID)
+}
+
+func customISOBootMessage(label string, instances []string) string {
+	return fmt.Sprintf("Instance array %s: the instances %s are rebooted from the custom ISO.", label, strings.Join(instances, ", "))
+}
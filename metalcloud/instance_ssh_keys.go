@@ -0,0 +1,141 @@
+package metalcloud
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+//The SSH keys of ssh_key_ids are delivered like the user data, in an authorized_keys file held by an OS asset of the
+//instance array that is attached to its OS template at instance_array_ssh_keys_path.
+
+//validateInstanceArraySSHKeys checks that the SSH keys exist and have an OS template to be delivered with
+func validateInstanceArraySSHKeys(d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("ssh_key_ids") || !d.HasChange("ssh_key_ids") {
+		return nil
+	}
+
+	ids := d.Get("ssh_key_ids").([]interface{})
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if d.NewValueKnown("volume_template_id") && d.Get("volume_template_id").(int) == 0 {
+		return fmt.Errorf("volume_template_id: the SSH keys are delivered through the assets of the OS template, set volume_template_id to an OS template")
+	}
+
+	keys, err := userSSHKeys(meta)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		//ids of keys created in the same apply are checked during apply
+		if id == nil || id.(int) == 0 {
+			continue
+		}
+
+		if _, ok := keys[id.(int)]; !ok {
+			return fmt.Errorf("ssh_key_ids: SSH key %d not found", id.(int))
+		}
+	}
+
+	oInstances, _ := d.GetChange("instances")
+
+	if labels := deployedInstanceLabels(oInstances.([]interface{})); len(labels) > 0 {
+		log.Printf("[WARN] %s", sshKeysReinstallMessage(d.Get("instance_array_label").(string), labels))
+	}
+
+	return nil
+}
+
+//updateSSHKeysOSAsset writes the SSH keys of ssh_key_ids in the OS asset kept in ssh_keys_os_asset_id and attaches it to
+//the OS template of the instance array
+func updateSSHKeysOSAsset(d *schema.ResourceData, instanceArrayID int, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if !d.HasChange("ssh_key_ids") && !d.HasChange("volume_template_id") && !d.HasChange("instance_array_ssh_keys_path") {
+		return diags
+	}
+
+	client := meta.(*mc.Client)
+
+	oTemplateID, nTemplateID := d.GetChange("volume_template_id")
+	templateID := nTemplateID.(int)
+	assetID := d.Get("ssh_keys_os_asset_id").(int)
+	ids := d.Get("ssh_key_ids").([]interface{})
+
+	if d.HasChange("volume_template_id") && oTemplateID.(int) != 0 {
+		if err := detachInstanceArrayOSAssets(oTemplateID.(int), assetID, nil, client); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	content := ""
+
+	if len(ids) > 0 {
+		if templateID == 0 {
+			return diag.Errorf("volume_template_id: the SSH keys are delivered through the assets of the OS template, set volume_template_id to an OS template")
+		}
+
+		keys, err := userSSHKeys(meta)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		var sb strings.Builder
+
+		for _, id := range ids {
+			k, ok := keys[id.(int)]
+			if !ok {
+				return diag.Errorf("ssh_key_ids: SSH key %d not found", id.(int))
+			}
+
+			sb.WriteString(strings.TrimSpace(k.UserSSHKey))
+			sb.WriteString("\n")
+		}
+
+		content = sb.String()
+	}
+
+	if templateID == 0 {
+		if err := deleteInstanceArrayOSAssets(0, assetID, nil, client); err != nil {
+			return diag.FromErr(err)
+		}
+
+		d.Set("ssh_keys_os_asset_id", 0)
+
+		return diags
+	}
+
+	attached, err := client.OSTemplateOSAssets(templateID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	assetID, err = updateInstanceArrayOSAsset(templateID, assetID, fmt.Sprintf("authorized-keys-%d", instanceArrayID), d.Get("instance_array_ssh_keys_path").(string), content, USER_DATA_ENCODING_PLAIN, *attached, client)
+	d.Set("ssh_keys_os_asset_id", assetID)
+
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if labels := deployedInstanceLabels(d.Get("instances").([]interface{})); d.HasChange("ssh_key_ids") && len(labels) > 0 {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  sshKeysReinstallMessage(d.Get("instance_array_label").(string), labels),
+		})
+	}
+
+	return diags
+}
+
+func sshKeysReinstallMessage(label string, instances []string) string {
+	return fmt.Sprintf("Instance array %s: the SSH keys changed. The deployed instances %s keep the previous keys until their OS is reinstalled.", label, strings.Join(instances, ", "))
+}
+
+const SSH_KEYS_DEFAULT_PATH = "/authorized_keys"
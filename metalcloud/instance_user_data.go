@@ -0,0 +1,517 @@
+package metalcloud
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+//The user data is delivered through the OS template asset mechanism. It is stored in OS assets owned by the instance
+//array that are attached to its OS template (volume_template_id): the user data of the instance array at
+//instance_array_user_data_path and the user data set on an instance at the same path followed by the instance label.
+//The assets are installed with the other files of the template, so the template must not be shared with other
+//instance arrays. The OS assets have no size limit, unlike the custom variables.
+//The user_data and user_data_encoding custom variables that older templates read the user data from are reserved
+//and removed wherever custom variables are read back.
+
+func validateUserDataEncoding(val interface{}, key string) (warns []string, errs []error) {
+	v := val.(string)
+	if v != USER_DATA_ENCODING_PLAIN && v != USER_DATA_ENCODING_BASE64 && v != USER_DATA_ENCODING_GZIP_BASE64 {
+		errs = append(errs, fmt.Errorf("%q must be one of '%s', '%s', '%s'. Provided value: %s", key, USER_DATA_ENCODING_PLAIN, USER_DATA_ENCODING_BASE64, USER_DATA_ENCODING_GZIP_BASE64, v))
+	}
+	return
+}
+
+func validateUserDataPath(val interface{}, key string) (warns []string, errs []error) {
+	v := val.(string)
+	if !strings.HasPrefix(v, "/") || strings.HasSuffix(v, "/") {
+		errs = append(errs, fmt.Errorf("%q must be an absolute file path. Provided value: %s", key, v))
+	}
+	return
+}
+
+//validateUserData checks that the user data can be decoded with the given encoding
+func validateUserData(userData string, encoding string) error {
+	_, err := decodeUserData(userData, encoding)
+	return err
+}
+
+//decodeUserData returns the content of the file delivered to the instances. Gzip compressed user data is delivered
+//compressed, cloud-init decompresses it.
+func decodeUserData(userData string, encoding string) ([]byte, error) {
+	if userData == "" || encoding == USER_DATA_ENCODING_PLAIN {
+		return []byte(userData), nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(userData)
+	if err != nil {
+		return nil, fmt.Errorf("the user data is not valid base64: %s", err)
+	}
+
+	if encoding != USER_DATA_ENCODING_GZIP_BASE64 {
+		return decoded, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(decoded))
+	if err != nil {
+		return nil, fmt.Errorf("the user data is not gzip compressed: %s. Use the base64gzip() function to encode it", err)
+	}
+
+	if _, err := ioutil.ReadAll(r); err != nil {
+		return nil, fmt.Errorf("the user data is not valid gzip: %s", err)
+	}
+
+	return decoded, nil
+}
+
+//validateInstanceArrayUserData checks the user data of the instance array and of its instances, that the reserved
+//custom variables are not set and that the user data has an OS template to be delivered with.
+func validateInstanceArrayUserData(d *schema.ResourceDiff) error {
+	for k := range d.Get("instance_array_custom_variables").(map[string]interface{}) {
+		if isReservedCustomVariable(k) {
+			return fmt.Errorf("instance_array_custom_variables: the %s custom variable is reserved, use instance_array_user_data instead", k)
+		}
+	}
+
+	hasUserData := false
+
+	if d.NewValueKnown("instance_array_user_data") {
+		userData := d.Get("instance_array_user_data").(string)
+		if err := validateUserData(userData, d.Get("instance_array_user_data_encoding").(string)); err != nil {
+			return fmt.Errorf("instance_array_user_data: %s", err)
+		}
+		hasUserData = userData != ""
+	}
+
+	if d.NewValueKnown("instance_custom_variables") {
+		for _, b := range d.Get("instance_custom_variables").([]interface{}) {
+			block := b.(map[string]interface{})
+
+			for k := range block["custom_variables"].(map[string]interface{}) {
+				if isReservedCustomVariable(k) {
+					return fmt.Errorf("instance_custom_variables: the %s custom variable is reserved, use user_data instead", k)
+				}
+			}
+
+			if err := validateUserData(block["user_data"].(string), block["user_data_encoding"].(string)); err != nil {
+				return fmt.Errorf("instance_custom_variables: %s", err)
+			}
+
+			hasUserData = hasUserData || block["user_data"].(string) != ""
+		}
+	}
+
+	if hasUserData && d.NewValueKnown("volume_template_id") && d.Get("volume_template_id").(int) == 0 {
+		return fmt.Errorf("volume_template_id: the user data is delivered through the assets of the OS template, set volume_template_id to an OS template")
+	}
+
+	return nil
+}
+
+//diffInstanceArrayUserData shows in user_data_reinstall_required the deployed instances that get another user data.
+//The user data is only used when the OS is installed so these instances keep the previous one until reinstalled.
+//The API does not report reinstalls so the list is only changed by the plans that change the user data.
+func diffInstanceArrayUserData(d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" || !(d.HasChange("instance_array_user_data") || d.HasChange("instance_array_user_data_encoding") || d.HasChange("instance_custom_variables")) {
+		return nil
+	}
+
+	if !d.NewValueKnown("instance_array_user_data") || !d.NewValueKnown("instance_custom_variables") {
+		return d.SetNewComputed("user_data_reinstall_required")
+	}
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return err
+	}
+
+	instanceList, err := meta.(*mc.Client).InstanceArrayInstances(id)
+	if err != nil {
+		return err
+	}
+
+	instances := sortInstances(*instanceList)
+
+	oUserData, nUserData := d.GetChange("instance_array_user_data")
+	oEncoding, nEncoding := d.GetChange("instance_array_user_data_encoding")
+	oBlocks, nBlocks := d.GetChange("instance_custom_variables")
+	oMapping, _ := d.GetChange("instance_index_mapping")
+
+	previous := instancesUserData(instances, oUserData.(string), oEncoding.(string), oBlocks.([]interface{}), oMapping)
+	configured := instancesUserData(instances, nUserData.(string), nEncoding.(string), nBlocks.([]interface{}), d.Get("instance_index_mapping"))
+
+	labels := userDataReinstallRequired(instances, previous, configured)
+
+	oList, _ := d.GetChange("user_data_reinstall_required")
+
+	if reflect.DeepEqual(labels, expandStringList(oList.([]interface{}))) {
+		return nil
+	}
+
+	if len(labels) > 0 {
+		log.Printf("[WARN] %s", userDataReinstallMessage(d.Get("instance_array_label").(string), labels))
+	}
+
+	return d.SetNew("user_data_reinstall_required", labels)
+}
+
+//userDataReinstallRequired returns the labels of the deployed instances whose user data differs
+func userDataReinstallRequired(instances []mc.Instance, previous map[int]string, next map[int]string) []string {
+	labels := []string{}
+
+	for _, i := range instances {
+		if i.ServerID == 0 || i.InstanceOperation.InstanceDeployType == DEPLOY_TYPE_DELETE {
+			continue
+		}
+
+		if previous[i.InstanceID] != next[i.InstanceID] {
+			labels = append(labels, i.InstanceLabel)
+		}
+	}
+
+	sort.Strings(labels)
+
+	return labels
+}
+
+//instancesUserData returns the configured encoding and user data of each instance
+func instancesUserData(instances []mc.Instance, userData string, encoding string, blocks []interface{}, mapping interface{}) map[int]string {
+	res := make(map[int]string, len(instances))
+
+	for _, i := range instances {
+		res[i.InstanceID] = userDataKey(userData, encoding)
+	}
+
+	for instanceID, u := range instancesOwnUserData(instances, blocks, mapping) {
+		res[instanceID] = userDataKey(u.userData, u.encoding)
+	}
+
+	return res
+}
+
+//instanceUserData is the user data set on an instance
+type instanceUserData struct {
+	label    string
+	userData string
+	encoding string
+}
+
+//instancesOwnUserData returns the user data set on the instances by the instance_custom_variables blocks
+func instancesOwnUserData(instances []mc.Instance, blocks []interface{}, mapping interface{}) map[int]instanceUserData {
+	res := map[int]instanceUserData{}
+
+	//the mapping is not known when the instance count changes, instances addressed by index are then skipped
+	m, _ := mapping.(map[string]interface{})
+
+	for _, b := range blocks {
+		block := b.(map[string]interface{})
+
+		blockUserData, _ := block["user_data"].(string)
+		if blockUserData == "" {
+			continue
+		}
+
+		instance, err := resolveInstanceAddress(block, instances, m)
+		if err != nil {
+			continue
+		}
+
+		blockEncoding, _ := block["user_data_encoding"].(string)
+		res[instance.InstanceID] = instanceUserData{instance.InstanceLabel, blockUserData, blockEncoding}
+	}
+
+	return res
+}
+
+func userDataKey(userData string, encoding string) string {
+	if userData == "" {
+		return ""
+	}
+
+	return encoding + ":" + userData
+}
+
+func userDataReinstallMessage(label string, instances []string) string {
+	return fmt.Sprintf("Instance array %s: the user data of the deployed instances %s changed. They keep the previous user data until their OS is reinstalled.", label, strings.Join(instances, ", "))
+}
+
+//updateUserDataOSAssets creates, updates and deletes the OS assets holding the user data and attaches them to the
+//OS template of the instance array. The ids of the assets are kept in user_data_os_asset_id and
+//instance_user_data_os_asset_ids.
+func updateUserDataOSAssets(d *schema.ResourceData, instanceArrayID int, mapping map[string]interface{}, client *mc.Client) diag.Diagnostics {
+	oTemplateID, nTemplateID := d.GetChange("volume_template_id")
+	templateID := nTemplateID.(int)
+	path := d.Get("instance_array_user_data_path").(string)
+
+	assetID := d.Get("user_data_os_asset_id").(int)
+	instanceAssetIDs := map[string]int{}
+	for label, id := range d.Get("instance_user_data_os_asset_ids").(map[string]interface{}) {
+		instanceAssetIDs[label] = id.(int)
+	}
+
+	//the assets are moved to the new template
+	if d.HasChange("volume_template_id") && oTemplateID.(int) != 0 {
+		if err := detachInstanceArrayOSAssets(oTemplateID.(int), assetID, instanceAssetIDs, client); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	instanceList, err := client.InstanceArrayInstances(instanceArrayID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	instances := sortInstances(*instanceList)
+
+	own := map[string]instanceUserData{}
+	for _, u := range instancesOwnUserData(instances, d.Get("instance_custom_variables").([]interface{}), mapping) {
+		own[u.label] = u
+	}
+
+	userData := d.Get("instance_array_user_data").(string)
+
+	if templateID == 0 {
+		if userData != "" || len(own) > 0 {
+			return diag.Errorf("volume_template_id: the user data is delivered through the assets of the OS template, set volume_template_id to an OS template")
+		}
+
+		if err := deleteInstanceArrayOSAssets(0, assetID, instanceAssetIDs, client); err != nil {
+			return diag.FromErr(err)
+		}
+
+		d.Set("user_data_os_asset_id", 0)
+		d.Set("instance_user_data_os_asset_ids", map[string]interface{}{})
+
+		return nil
+	}
+
+	attached, err := client.OSTemplateOSAssets(templateID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	assetID, err = updateInstanceArrayOSAsset(templateID, assetID, fmt.Sprintf("user-data-%d", instanceArrayID), path, userData, d.Get("instance_array_user_data_encoding").(string), *attached, client)
+	d.Set("user_data_os_asset_id", assetID)
+
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	for label, id := range instanceAssetIDs {
+		if _, ok := own[label]; ok {
+			continue
+		}
+
+		if _, err := updateInstanceArrayOSAsset(templateID, id, "", "", "", "", *attached, client); err != nil {
+			d.Set("instance_user_data_os_asset_ids", instanceAssetIDs)
+			return diag.FromErr(err)
+		}
+
+		delete(instanceAssetIDs, label)
+	}
+
+	for label, u := range own {
+		id, err := updateInstanceArrayOSAsset(templateID, instanceAssetIDs[label], fmt.Sprintf("user-data-%d-%s", instanceArrayID, label), path+"-"+label, u.userData, u.encoding, *attached, client)
+		instanceAssetIDs[label] = id
+
+		if err != nil {
+			d.Set("instance_user_data_os_asset_ids", instanceAssetIDs)
+			return diag.FromErr(err)
+		}
+	}
+
+	d.Set("instance_user_data_os_asset_ids", instanceAssetIDs)
+
+	return nil
+}
+
+//updateInstanceArrayOSAsset makes the OS asset hold the user data, or another file of the instance array, and attaches
+//it to the template at the path. Without content the asset is deleted. It returns the id of the asset, 0 once deleted.
+func updateInstanceArrayOSAsset(templateID int, assetID int, filename string, path string, content string, encoding string, attached map[string]mc.OSTemplateOSAssetData, client *mc.Client) (int, error) {
+	if content == "" {
+		detachFrom := 0
+		for _, a := range attached {
+			if a.OSAsset != nil && a.OSAsset.OSAssetID == assetID {
+				detachFrom = templateID
+			}
+		}
+
+		if err := deleteInstanceArrayOSAssets(detachFrom, assetID, nil, client); err != nil {
+			return assetID, err
+		}
+
+		return 0, nil
+	}
+
+	decoded, err := decodeUserData(content, encoding)
+	if err != nil {
+		return assetID, err
+	}
+
+	for _, a := range attached {
+		if a.OSAsset == nil || a.OSAssetFilePath != path || a.OSAsset.OSAssetID == assetID {
+			continue
+		}
+
+		return assetID, fmt.Errorf("the OS template %d already has the OS asset %s at %s. Use an OS template dedicated to the instance array", templateID, a.OSAsset.OSAssetFileName, path)
+	}
+
+	asset := mc.OSAsset{
+		OSAssetFileName:       filename,
+		OSAssetFileMime:       USER_DATA_MIME_PLAIN,
+		OSAssetContentsBase64: base64.StdEncoding.EncodeToString(decoded),
+		OSAssetTags:           []string{INSTANCE_ARRAY_OS_ASSET_TAG},
+	}
+
+	if encoding == USER_DATA_ENCODING_GZIP_BASE64 {
+		asset.OSAssetFileMime = USER_DATA_MIME_GZIP
+	}
+
+	sum := sha256.Sum256(decoded)
+
+	var existing *mc.OSAsset
+
+	if assetID != 0 {
+		existing, err = client.OSAssetGet(assetID)
+		if err != nil && !isNotFoundError(err) {
+			return assetID, err
+		}
+	}
+
+	switch {
+	case existing == nil:
+		created, err := client.OSAssetCreate(asset)
+		if err != nil {
+			return 0, fmt.Errorf("could not create the OS asset %s: %s", filename, err)
+		}
+		assetID = created.OSAssetID
+	case existing.OSAssetContentsSHA256Hex != hex.EncodeToString(sum[:]) || existing.OSAssetFileMime != asset.OSAssetFileMime:
+		if _, err := client.OSAssetUpdate(assetID, asset); err != nil {
+			return assetID, fmt.Errorf("could not update the OS asset %s: %s", filename, err)
+		}
+	}
+
+	for _, a := range attached {
+		if a.OSAsset == nil || a.OSAsset.OSAssetID != assetID {
+			continue
+		}
+
+		if a.OSAssetFilePath != path {
+			if err := client.OSTemplateUpdateOSAssetPath(templateID, assetID, path); err != nil {
+				return assetID, fmt.Errorf("could not update the path of the OS asset %s: %s", filename, err)
+			}
+		}
+
+		return assetID, nil
+	}
+
+	if err := client.OSTemplateAddOSAsset(templateID, assetID, path, ""); err != nil {
+		return assetID, fmt.Errorf("could not add the OS asset %s to the OS template %d: %s", filename, templateID, err)
+	}
+
+	return assetID, nil
+}
+
+//detachInstanceArrayOSAssets removes the OS assets of the instance array from the template
+func detachInstanceArrayOSAssets(templateID int, assetID int, instanceAssetIDs map[string]int, client *mc.Client) error {
+	ids := []int{assetID}
+	for _, id := range instanceAssetIDs {
+		ids = append(ids, id)
+	}
+
+	for _, id := range ids {
+		if id == 0 {
+			continue
+		}
+
+		if err := client.OSTemplateRemoveOSAsset(templateID, id); err != nil && !isNotFoundError(err) {
+			return fmt.Errorf("could not remove the OS asset %d from the OS template %d: %s", id, templateID, err)
+		}
+	}
+
+	return nil
+}
+
+//deleteInstanceArrayOSAssets removes the OS assets of the instance array from the template, when set, and deletes them
+func deleteInstanceArrayOSAssets(templateID int, assetID int, instanceAssetIDs map[string]int, client *mc.Client) error {
+	if templateID != 0 {
+		if err := detachInstanceArrayOSAssets(templateID, assetID, instanceAssetIDs, client); err != nil {
+			return err
+		}
+	}
+
+	ids := []int{assetID}
+	for _, id := range instanceAssetIDs {
+		ids = append(ids, id)
+	}
+
+	for _, id := range ids {
+		if id == 0 {
+			continue
+		}
+
+		if err := client.OSAssetDelete(id); err != nil && !isNotFoundError(err) {
+			return fmt.Errorf("could not delete the OS asset %d: %s", id, err)
+		}
+	}
+
+	return nil
+}
+
+//isInstanceArrayOSAsset returns true for the OS assets created to deliver the user data and the SSH keys of instance arrays
+func isInstanceArrayOSAsset(a *mc.OSAsset) bool {
+	if a == nil {
+		return false
+	}
+
+	for _, t := range a.OSAssetTags {
+		if t == INSTANCE_ARRAY_OS_ASSET_TAG {
+			return true
+		}
+	}
+
+	return false
+}
+
+//isReservedCustomVariable returns true for the custom variables that carried the user data before it was delivered
+//through OS assets
+func isReservedCustomVariable(name string) bool {
+	return name == USER_DATA_CUSTOM_VARIABLE || name == USER_DATA_ENCODING_CUSTOM_VARIABLE
+}
+
+//removeReservedCustomVariables removes the reserved custom variables so that the user data they may still hold is
+//not read back
+func removeReservedCustomVariables(customVariables map[string]interface{}) map[string]interface{} {
+	for k := range customVariables {
+		if isReservedCustomVariable(k) {
+			delete(customVariables, k)
+		}
+	}
+
+	return customVariables
+}
+
+const USER_DATA_CUSTOM_VARIABLE = "user_data"
+const USER_DATA_ENCODING_CUSTOM_VARIABLE = "user_data_encoding"
+
+const USER_DATA_ENCODING_PLAIN = "plain"
+const USER_DATA_ENCODING_BASE64 = "base64"
+const USER_DATA_ENCODING_GZIP_BASE64 = "gzip+base64"
+
+const USER_DATA_DEFAULT_PATH = "/user-data"
+const INSTANCE_ARRAY_OS_ASSET_TAG = "instance_array_os_asset"
+const USER_DATA_MIME_PLAIN = "text/plain"
+const USER_DATA_MIME_GZIP = "application/gzip"
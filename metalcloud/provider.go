@@ -17,24 +17,36 @@ func Provider() *schema.Provider {
 
 func providerResources() map[string]*schema.Resource {
 	return map[string]*schema.Resource{
+		"metalcloud_infrastructure":          ResourceInfrastructure(),
 		"metalcloud_infrastructure_deployer": ResourceInfrastructureDeployer(),
 		"metalcloud_instance_array":          resourceInstanceArray(),
 		"metalcloud_drive_array":             resourceDriveArray(),
 		"metalcloud_shared_drive":            resourceSharedDrive(),
 		"metalcloud_network":                 resourceNetwork(),
 		"metalcloud_network_profile":         resourceNetworkProfile(),
+		"metalcloud_subnet":                  resourceSubnet(),
+		"metalcloud_firewall_rule_set":       resourceFirewallRuleSet(),
 		// "metalcloud_external_connection":     resourceExternalConnection(),
 		"metalcloud_firmware_policy": resourceServerFirmwareUpgradePolicy(),
+		"metalcloud_os_template":     resourceOSTemplate(),
+		"metalcloud_os_asset":        resourceOSAsset(),
+		"metalcloud_custom_iso":      resourceCustomISO(),
+		"metalcloud_ssh_key":         resourceSSHKey(),
 	}
 }
 
 func providerDataSources() map[string]*schema.Resource {
 	return map[string]*schema.Resource{
 		"metalcloud_volume_template":       DataSourceVolumeTemplate(),
+		"metalcloud_volume_templates":      DataSourceVolumeTemplates(),
 		"metalcloud_infrastructure":        DataSourceInfrastructureReference(),
+		"metalcloud_infrastructures":       DataSourceInfrastructures(),
 		"metalcloud_external_connection":   DataSourceExternalConnection(),
 		"metalcloud_server_type":           DataSourceServerType(),
+		"metalcloud_server_types":          DataSourceServerTypes(),
 		"metalcloud_infrastructure_output": DataSourceInfrastructureOutput(),
+		"metalcloud_ansible_inventory":     DataSourceAnsibleInventory(),
+		"metalcloud_ssh_config":            DataSourceSSHConfig(),
 	}
 }
 
@@ -100,5 +112,19 @@ func providerConfigure(d *schema.ResourceData) (interface{}, error) {
 		return nil, err
 	}
 
+	api, err := newAPIClient(
+		d.Get("api_key").(string),
+		d.Get("endpoint").(string),
+		d.Get("logging").(bool),
+		d.Get("user_id").(string),
+		d.Get("user_secret").(string),
+		d.Get("oauth_token_url").(string),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	registerAPIClient(client, api)
+
 	return client, nil
 }
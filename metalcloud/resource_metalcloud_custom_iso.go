@@ -0,0 +1,190 @@
+package metalcloud
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+//customISO is the custom ISO object of the API, which is not part of the SDK
+type customISO struct {
+	CustomISOID             int    `json:"custom_iso_id,omitempty"`
+	UserIDOwner             int    `json:"user_id_owner,omitempty"`
+	CustomISOName           string `json:"custom_iso_name,omitempty"`
+	CustomISODisplayName    string `json:"custom_iso_display_name,omitempty"`
+	CustomISOType           string `json:"custom_iso_type,omitempty"`
+	CustomISOAccessURL      string `json:"custom_iso_access_url,omitempty"`
+	CustomISOAccessUsername string `json:"custom_iso_access_username,omitempty"`
+	CustomISOAccessPassword string `json:"custom_iso_access_password,omitempty"`
+}
+
+//resourceCustomISO manages an ISO image that servers can boot from through their out of band interface
+func resourceCustomISO() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceCustomISOCreate,
+		ReadContext:   resourceCustomISORead,
+		UpdateContext: resourceCustomISOUpdate,
+		DeleteContext: resourceCustomISODelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		Schema: map[string]*schema.Schema{
+			"custom_iso_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"custom_iso_label": &schema.Schema{
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateDiagFunc: validateLabel,
+			},
+			"custom_iso_display_name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"custom_iso_access_url": &schema.Schema{
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateCustomISOAccessURL,
+			},
+			"custom_iso_access_username": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"custom_iso_access_password": &schema.Schema{
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+		},
+	}
+}
+
+func resourceCustomISOCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	api, err := getAPIClient(meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var created customISO
+
+	if err := api.call(&created, "custom_iso_create", api.userID, expandCustomISO(d)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("%d", created.CustomISOID))
+
+	return resourceCustomISORead(ctx, d, meta)
+}
+
+func resourceCustomISORead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	api, err := getAPIClient(meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var iso customISO
+
+	if err := api.call(&iso, "custom_iso_get", id); err != nil {
+		if isNotFoundError(err) {
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(err)
+	}
+
+	d.Set("custom_iso_id", iso.CustomISOID)
+	d.Set("custom_iso_label", iso.CustomISOName)
+	d.Set("custom_iso_display_name", iso.CustomISODisplayName)
+	d.Set("custom_iso_access_url", iso.CustomISOAccessURL)
+	d.Set("custom_iso_access_username", iso.CustomISOAccessUsername)
+
+	//the password is not returned by the API
+	if iso.CustomISOAccessPassword != "" {
+		d.Set("custom_iso_access_password", iso.CustomISOAccessPassword)
+	}
+
+	return diags
+}
+
+func resourceCustomISOUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	api, err := getAPIClient(meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var updated customISO
+
+	if err := api.call(&updated, "custom_iso_update", id, expandCustomISO(d)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceCustomISORead(ctx, d, meta)
+}
+
+func resourceCustomISODelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	api, err := getAPIClient(meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var deleted interface{}
+
+	if err := api.call(&deleted, "custom_iso_delete", id); err != nil && !isNotFoundError(err) {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+
+	return diags
+}
+
+func expandCustomISO(d *schema.ResourceData) customISO {
+	return customISO{
+		CustomISOName:           d.Get("custom_iso_label").(string),
+		CustomISODisplayName:    d.Get("custom_iso_display_name").(string),
+		CustomISOType:           CUSTOM_ISO_TYPE_ISO,
+		CustomISOAccessURL:      d.Get("custom_iso_access_url").(string),
+		CustomISOAccessUsername: d.Get("custom_iso_access_username").(string),
+		CustomISOAccessPassword: d.Get("custom_iso_access_password").(string),
+	}
+}
+
+//validateCustomISOAccessURL checks that the ISO is served over HTTP or HTTPS, the protocols the out of band interfaces
+//of the servers can mount it from
+func validateCustomISOAccessURL(val interface{}, key string) (warns []string, errs []error) {
+	v := val.(string)
+
+	u, err := url.Parse(v)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		errs = append(errs, fmt.Errorf("%q must be an http or https URL. Provided value: %s", key, v))
+	}
+
+	return
+}
+
+const CUSTOM_ISO_TYPE_ISO = "iso"
@@ -0,0 +1,292 @@
+package metalcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+//resourceFirewallRuleSet manages a named list of firewall rules that can be applied on multiple instance arrays.
+//There is no firewall rule set object on the server so the rules are stored as JSON in a user variable.
+func resourceFirewallRuleSet() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceFirewallRuleSetCreate,
+		ReadContext:   resourceFirewallRuleSetRead,
+		UpdateContext: resourceFirewallRuleSetUpdate,
+		DeleteContext: resourceFirewallRuleSetDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		CustomizeDiff: resourceFirewallRuleSetCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"firewall_rule_set_label": &schema.Schema{
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateDiagFunc: validateLabel,
+			},
+			"firewall_rule": {
+				Type:     schema.TypeSet,
+				Required: true,
+				Elem:     resourceFirewallRule(),
+			},
+			"allow_redundant_firewall_rules": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"firewall_rule_set_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+		},
+	}
+}
+
+//resourceFirewallRuleSetCustomizeDiff validates the rules and marks firewall_rule_set_id as changing when they do,
+//so that instance arrays referencing it are updated in the same apply.
+func resourceFirewallRuleSetCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("firewall_rule") {
+		return nil
+	}
+
+	rules, err := expandFirewallRuleSetRules(d.Get("firewall_rule").(*schema.Set).List())
+	if err != nil {
+		return err
+	}
+
+	warnings, err := validateFirewallRules(rules)
+	if err != nil {
+		return err
+	}
+
+	if err := checkFirewallRuleWarnings(warnings, d.Get("allow_redundant_firewall_rules").(bool)); err != nil {
+		return err
+	}
+
+	if d.Id() != "" && d.HasChange("firewall_rule") {
+		return d.SetNewComputed("firewall_rule_set_id")
+	}
+
+	return nil
+}
+
+func resourceFirewallRuleSetCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*mc.Client)
+
+	v, err := expandFirewallRuleSet(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := checkFirewallRuleSetVariableName(v.VariableName, 0, client); err != nil {
+		return diag.FromErr(err)
+	}
+
+	vC, err := client.VariableCreate(*v)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("%d", vC.VariableID))
+
+	return append(firewallRuleSetDiagnostics(d), resourceFirewallRuleSetRead(ctx, d, meta)...)
+}
+
+func resourceFirewallRuleSetRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	client := meta.(*mc.Client)
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	v, err := client.VariableGet(id)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if !isFirewallRuleSetVariable(v) {
+		return diag.Errorf("Variable %s (#%d) is not a firewall rule set", v.VariableName, id)
+	}
+
+	rules, err := flattenFirewallRuleSetRules(v.VariableJSON)
+	if err != nil {
+		return diag.Errorf("Variable %s (#%d) is not a firewall rule set: %s", v.VariableName, id, err)
+	}
+
+	configured := []interface{}{}
+	if fwRulesSet, ok := d.Get("firewall_rule").(*schema.Set); ok {
+		configured = fwRulesSet.List()
+	}
+
+	d.Set("firewall_rule_set_id", v.VariableID)
+	d.Set("firewall_rule_set_label", strings.TrimPrefix(v.VariableName, FIREWALL_RULE_SET_VARIABLE_PREFIX))
+	d.Set("firewall_rule", schema.NewSet(schema.HashResource(resourceFirewallRule()), flattenFirewallRules(rules, configured)))
+
+	return diags
+}
+
+func resourceFirewallRuleSetUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*mc.Client)
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	v, err := expandFirewallRuleSet(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if d.HasChange("firewall_rule_set_label") {
+		if err := checkFirewallRuleSetVariableName(v.VariableName, id, client); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	if _, err := client.VariableUpdate(id, *v); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return append(firewallRuleSetDiagnostics(d), resourceFirewallRuleSetRead(ctx, d, meta)...)
+}
+
+func resourceFirewallRuleSetDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	client := meta.(*mc.Client)
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := client.VariableDelete(id); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+
+	return diags
+}
+
+//firewallRuleSetDiagnostics returns the warnings about the rules of the set
+func firewallRuleSetDiagnostics(d *schema.ResourceData) diag.Diagnostics {
+	rules, err := expandFirewallRuleSetRules(d.Get("firewall_rule").(*schema.Set).List())
+	if err != nil {
+		return nil
+	}
+
+	return firewallRuleDiagnostics(rules)
+}
+
+func expandFirewallRuleSet(d *schema.ResourceData) (*mc.Variable, error) {
+	rules, err := expandFirewallRuleSetRules(d.Get("firewall_rule").(*schema.Set).List())
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mc.Variable{
+		VariableName:  FIREWALL_RULE_SET_VARIABLE_PREFIX + d.Get("firewall_rule_set_label").(string),
+		VariableUsage: FIREWALL_RULE_SET_VARIABLE_USAGE,
+		VariableJSON:  string(bytes),
+	}, nil
+}
+
+//isFirewallRuleSetVariable returns true if the variable stores a firewall rule set. Sets created before the variables
+//were marked with FIREWALL_RULE_SET_VARIABLE_USAGE only have the name prefix and are marked on their next update.
+func isFirewallRuleSetVariable(v *mc.Variable) bool {
+	if v.VariableUsage == FIREWALL_RULE_SET_VARIABLE_USAGE {
+		return true
+	}
+
+	return v.VariableUsage == "" && strings.HasPrefix(v.VariableName, FIREWALL_RULE_SET_VARIABLE_PREFIX)
+}
+
+//checkFirewallRuleSetVariableName fails if a variable other than the one of the set already uses the name, as the
+//set would otherwise overwrite or be confused with a variable created outside terraform
+func checkFirewallRuleSetVariableName(name string, id int, client *mc.Client) error {
+	variables, err := client.Variables("")
+	if err != nil {
+		return err
+	}
+
+	for _, v := range *variables {
+		if v.VariableName == name && v.VariableID != id {
+			return fmt.Errorf("firewall_rule_set_label: variable %s (#%d) already exists. Import it or use a different label", name, v.VariableID)
+		}
+	}
+
+	return nil
+}
+
+//expandFirewallRuleSetRules expands firewall_rule blocks, the CIDRs are stored as ranges
+func expandFirewallRuleSetRules(fwRules []interface{}) ([]mc.FirewallRule, error) {
+	rules := []mc.FirewallRule{}
+
+	for _, fwMap := range fwRules {
+		fw, err := expandFirewallRuleMap(fwMap.(map[string]interface{}))
+		if err != nil {
+			return nil, fmt.Errorf("firewall_rule: %s", err)
+		}
+		rules = append(rules, fw)
+	}
+
+	return rules, nil
+}
+
+func flattenFirewallRuleSetRules(variableJSON string) ([]mc.FirewallRule, error) {
+	rules := []mc.FirewallRule{}
+
+	if err := json.Unmarshal([]byte(variableJSON), &rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+//firewallRuleSetsRules returns the rules of all the given firewall rule sets
+func firewallRuleSetsRules(firewallRuleSetIDs []interface{}, client *mc.Client) ([]mc.FirewallRule, error) {
+	rules := []mc.FirewallRule{}
+
+	for _, idIntf := range firewallRuleSetIDs {
+		id := idIntf.(int)
+
+		v, err := client.VariableGet(id)
+		if err != nil {
+			return nil, fmt.Errorf("firewall_rule_set_ids: could not retrieve firewall rule set %d: %s", id, err)
+		}
+
+		if !isFirewallRuleSetVariable(v) {
+			return nil, fmt.Errorf("firewall_rule_set_ids: variable %s (#%d) is not a firewall rule set", v.VariableName, id)
+		}
+
+		setRules, err := flattenFirewallRuleSetRules(v.VariableJSON)
+		if err != nil {
+			return nil, fmt.Errorf("firewall_rule_set_ids: %d is not a firewall rule set: %s", id, err)
+		}
+
+		rules = append(rules, setRules...)
+	}
+
+	return rules, nil
+}
+
+const (
+	FIREWALL_RULE_SET_VARIABLE_PREFIX = "firewall-rule-set-"
+	FIREWALL_RULE_SET_VARIABLE_USAGE  = "terraform_firewall_rule_set"
+)
@@ -0,0 +1,225 @@
+package metalcloud
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+//ResourceInfrastructure manages the lifecycle of an infrastructure. It replaces the create_if_not_exists
+//behaviour of the metalcloud_infrastructure data source.
+func ResourceInfrastructure() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceInfrastructureCreate,
+		ReadContext:   resourceInfrastructureRead,
+		UpdateContext: resourceInfrastructureUpdate,
+		DeleteContext: resourceInfrastructureDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		Schema: map[string]*schema.Schema{
+			"infrastructure_label": {
+				Type:     schema.TypeString,
+				Required: true,
+				//this required as the serverside will convert to lowercase and generate a diff
+				ValidateDiagFunc: validateLabel,
+				DiffSuppressFunc: func(_, old, new string, d *schema.ResourceData) bool {
+					if strings.ToLower(old) == strings.ToLower(new) {
+						return true
+					}
+
+					if new == "" {
+						return true
+					}
+					return false
+				},
+			},
+			"datacenter_name": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validateLabel,
+			},
+			"infrastructure_custom_variables": {
+				Type:     schema.TypeMap,
+				Elem:     schema.TypeString,
+				Optional: true,
+			},
+			"deletion_protection": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"infrastructure_id": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"infrastructure_service_status": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceInfrastructureCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*mc.Client)
+
+	infrastructureLabel := d.Get("infrastructure_label").(string)
+
+	infrastructures, err := client.Infrastructures()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if _, ok := (*infrastructures)[strings.ToLower(infrastructureLabel)]; ok {
+		return diag.Errorf("An infrastructure with label %s already exists. Use terraform import to manage it.", infrastructureLabel)
+	}
+
+	i := mc.Infrastructure{
+		InfrastructureLabel: infrastructureLabel,
+		DatacenterName:      d.Get("datacenter_name").(string),
+	}
+
+	iRet, err := client.InfrastructureCreate(i)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("%d", iRet.InfrastructureID))
+
+	//custom variables can only be set with an edit operation
+	if _, ok := d.GetOk("infrastructure_custom_variables"); ok {
+		return resourceInfrastructureUpdate(ctx, d, meta)
+	}
+
+	return resourceInfrastructureRead(ctx, d, meta)
+}
+
+func resourceInfrastructureRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	client := meta.(*mc.Client)
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	infrastructure, err := client.InfrastructureGet(id)
+	if err != nil {
+		//deleted outside terraform, it is created again on the next apply
+		if isNotFoundError(err) {
+			d.SetId("")
+			return diags
+		}
+
+		return diag.FromErr(err)
+	}
+
+	if infrastructure.InfrastructureServiceStatus == SERVICE_STATUS_DELETED {
+		d.SetId("")
+		return diags
+	}
+
+	d.Set("infrastructure_id", infrastructure.InfrastructureID)
+	d.Set("infrastructure_label", infrastructure.InfrastructureLabel)
+	d.Set("datacenter_name", infrastructure.DatacenterName)
+	d.Set("infrastructure_service_status", infrastructure.InfrastructureServiceStatus)
+
+	if err := d.Set("infrastructure_custom_variables", flattenInfrastructureCustomVariables(infrastructure.InfrastructureCustomVariables)); err != nil {
+		return diag.Errorf("error setting infrastructure custom variables %s", err)
+	}
+
+	return diags
+}
+
+func resourceInfrastructureUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*mc.Client)
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	infrastructure, err := client.InfrastructureGet(id)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	operation := infrastructure.InfrastructureOperation
+	operation.InfrastructureLabel = d.Get("infrastructure_label").(string)
+
+	cv := make(map[string]string)
+
+	for k, v := range d.Get("infrastructure_custom_variables").(map[string]interface{}) {
+		cv[k] = v.(string)
+	}
+
+	operation.InfrastructureCustomVariables = cv
+
+	if _, err := client.InfrastructureEdit(id, operation); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceInfrastructureRead(ctx, d, meta)
+}
+
+//resourceInfrastructureDelete marks the infrastructure as deleted. If the infrastructure has been deployed
+//the deletion needs to be deployed as well, which is done by the metalcloud_infrastructure_deployer resource.
+func resourceInfrastructureDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	client := meta.(*mc.Client)
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	infrastructure, err := client.InfrastructureGet(id)
+	if err != nil {
+		//already deleted, possibly by the infrastructure deployer
+		if isNotFoundError(err) {
+			d.SetId("")
+			return diags
+		}
+
+		return diag.FromErr(err)
+	}
+
+	if infrastructure.InfrastructureServiceStatus == SERVICE_STATUS_DELETED {
+		d.SetId("")
+		return diags
+	}
+
+	if d.Get("deletion_protection").(bool) {
+		return diag.Errorf("Cannot delete infrastructure %s (#%d) because deletion_protection is set to true. Set it to false and apply before destroying.", infrastructure.InfrastructureLabel, id)
+	}
+
+	if err := client.InfrastructureDelete(id); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+
+	return diags
+}
+
+func flattenInfrastructureCustomVariables(customVariables interface{}) map[string]string {
+	icv := make(map[string]string)
+
+	switch customVariables.(type) {
+	case map[string]interface{}:
+		for k, v := range customVariables.(map[string]interface{}) {
+			icv[k] = v.(string)
+		}
+	}
+
+	return icv
+}
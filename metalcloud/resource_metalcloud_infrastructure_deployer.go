@@ -101,10 +101,15 @@ func ResourceInfrastructureDeployer() *schema.Resource {
 				Type:     schema.TypeBool,
 				Computed: true,
 			},
+			"deploy_ongoing": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
 		},
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(45 * time.Minute),
 			Update: schema.DefaultTimeout(45 * time.Minute),
+			Delete: schema.DefaultTimeout(45 * time.Minute),
 		},
 	}
 }
@@ -153,6 +158,13 @@ func resourceServerAllocationPolicy() *schema.Resource {
 //introduce a fake edit to allow us to deploy.
 func resourceInfrastructureDeployerCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
 
+	//a previous run was interrupted while waiting for a deploy, the update reattaches to it
+	if d.Id() != "" && d.Get("deploy_ongoing").(bool) {
+		if err := d.SetNewComputed("deploy_ongoing"); err != nil {
+			return err
+		}
+	}
+
 	if !d.Get("prevent_deploy").(bool) {
 		d.SetNew("edited", true)
 	}
@@ -234,10 +246,34 @@ func resourceInfrastructureDeployerUpdate(ctx context.Context, d *schema.Resourc
 	needsDeploy := d.Get("edited").(bool)
 	preventDeploy := d.Get("prevent_deploy").(bool)
 
+	//a deploy started from the UI, by another pipeline or by a previous interrupted run might still be running.
+	//We wait for it to finish instead of failing and then check if another deploy is still needed.
+	reattachedDeployType := ""
+
+	//deploy_ongoing is known after apply when it was set, the previous value is the one in the state
+	wasOngoing, _ := d.GetChange("deploy_ongoing")
+
+	if (needsDeploy && !preventDeploy) || wasOngoing.(bool) {
+		deployType, dg := waitForOngoingDeploy(infrastructure_id, ctx, d, meta, d.Timeout(schema.TimeoutUpdate))
+		if dg.HasError() || d.Get("deploy_ongoing").(bool) {
+			return dg
+		}
+
+		if deployType == DEPLOY_TYPE_DELETE {
+			return diag.Errorf("Infrastructure #%d has been deleted by a deploy that was already in progress.", infrastructure_id)
+		}
+
+		reattachedDeployType = deployType
+	}
+
 	updateInfrastructureCustomVariables(d, infrastructure_id, client)
 
 	//This is where the magic happens.
 	if needsDeploy && !preventDeploy {
+		if err := ctx.Err(); err != nil {
+			return diag.FromErr(err)
+		}
+
 		d.Set("edited", false) //clear the taint flag. This ensures that we will be able to deploy again next time
 
 		err := deployInfrastructure(infrastructure_id, d, meta)
@@ -250,6 +286,11 @@ func resourceInfrastructureDeployerUpdate(ctx context.Context, d *schema.Resourc
 			}
 
 			if strings.Contains(err.Error(), UNMODIFIED_INFRASTRUCTURE_WARNING) {
+				//the deploy we waited for has already applied all the changes
+				if reattachedDeployType != "" {
+					return nil
+				}
+
 				var diags diag.Diagnostics
 
 				diags = append(diags, diag.Diagnostic{
@@ -319,6 +360,23 @@ func resourceInfrastructureDeployerDelete(ctx context.Context, d *schema.Resourc
 		return nil
 	}
 
+	//a deploy might already be running, started from the UI, by another pipeline or by a previous interrupted run
+	deployType, dg := waitForOngoingDeploy(infrastructureID, ctx, d, meta, d.Timeout(schema.TimeoutDelete))
+	if dg.HasError() {
+		return dg
+	}
+
+	//the infrastructure must stay in the state until it is deleted
+	if d.Get("deploy_ongoing").(bool) {
+		return diag.Errorf("Interrupted while waiting for the deploy of infrastructure #%d to finish. The deploy is still running.", infrastructureID)
+	}
+
+	if deployType == DEPLOY_TYPE_DELETE {
+		d.SetId("")
+
+		return nil
+	}
+
 	if err := client.InfrastructureDelete(infrastructureID); err != nil {
 		return diag.FromErr(err)
 	}
@@ -332,11 +390,15 @@ func resourceInfrastructureDeployerDelete(ctx context.Context, d *schema.Resourc
 			return diag.FromErr(err)
 		}
 
-		dg := waitForInfrastructureFinished(infrastructureID, ctx, d, meta, d.Timeout(schema.TimeoutUpdate), DEPLOY_STATUS_DELETED)
+		dg := waitForInfrastructureFinished(infrastructureID, ctx, d, meta, d.Timeout(schema.TimeoutDelete), DEPLOY_STATUS_DELETED)
 
 		if dg.HasError() {
 			return dg
 		}
+
+		if d.Get("deploy_ongoing").(bool) {
+			return diag.Errorf("Interrupted while waiting for the delete of infrastructure #%d to finish. The deploy is still running.", infrastructureID)
+		}
 	}
 
 	d.SetId("")
@@ -344,11 +406,17 @@ func resourceInfrastructureDeployerDelete(ctx context.Context, d *schema.Resourc
 	return nil
 }
 
-//waitForInfrastructureFinished awaits for the "finished" status in the specified infrastructure
+//waitForInfrastructureFinished awaits for the "finished" status in the specified infrastructure.
+//Until the wait completes the deploy is recorded as ongoing in the state so that if the wait is interrupted
+//(Ctrl-C or timeout) the next run will reattach to the deploy. An interruption is an error so that resources depending
+//on the deployer are not changed against a half deployed infrastructure, except on create where it is a warning:
+//a failed create taints the deployer and replacing it would delete the infrastructure.
 func waitForInfrastructureFinished(infrastructureID int, ctx context.Context, d *schema.ResourceData, meta interface{}, timeout time.Duration, targetStatus string) diag.Diagnostics {
 
 	client := meta.(*mc.Client)
 
+	d.Set("deploy_ongoing", true)
+
 	createStateConf := &resource.StateChangeConf{
 		Pending: []string{
 			DEPLOY_STATUS_NOT_STARTED,
@@ -375,10 +443,27 @@ func waitForInfrastructureFinished(infrastructureID int, ctx context.Context, d
 		ContinuousTargetOccurence: 1,
 	}
 
-	if _, err := createStateConf.WaitForState(); err != nil {
-		return diag.Errorf("Error waiting for example instance (%s) to be created: %s", d.Id(), err)
+	if _, err := createStateConf.WaitForStateContext(ctx); err != nil {
+		if _, timeout := err.(*resource.TimeoutError); timeout || ctx.Err() != nil {
+			severity := diag.Error
+			if d.IsNewResource() {
+				severity = diag.Warning
+			}
+
+			return diag.Diagnostics{
+				diag.Diagnostic{
+					Severity: severity,
+					Summary:  fmt.Sprintf("Interrupted while waiting for the deploy of infrastructure #%d to finish.", infrastructureID),
+					Detail:   "The deploy is still running. The next apply will wait for it instead of starting a new one.",
+				},
+			}
+		}
+
+		return diag.Errorf("Error waiting for the deploy of infrastructure #%d to finish: %s", infrastructureID, err)
 	}
 
+	d.Set("deploy_ongoing", false)
+
 	if targetStatus == DEPLOY_STATUS_DELETED {
 		return nil
 	}
@@ -387,6 +472,33 @@ func waitForInfrastructureFinished(infrastructureID int, ctx context.Context, d
 
 }
 
+//waitForOngoingDeploy checks InfrastructureDeployStatus and waits for a deploy that is already running on the infrastructure.
+//It returns the type of the deploy it waited for or an empty string if there was no deploy in progress.
+func waitForOngoingDeploy(infrastructureID int, ctx context.Context, d *schema.ResourceData, meta interface{}, timeout time.Duration) (string, diag.Diagnostics) {
+	client := meta.(*mc.Client)
+
+	infrastructure, err := client.InfrastructureGet(infrastructureID)
+	if err != nil {
+		return "", diag.FromErr(err)
+	}
+
+	operation := infrastructure.InfrastructureOperation
+
+	if operation.InfrastructureDeployStatus != DEPLOY_STATUS_ONGOING {
+		d.Set("deploy_ongoing", false)
+		return "", nil
+	}
+
+	log.Printf("Waiting for the ongoing %s deploy of infrastructure #%d", operation.InfrastructureDeployType, infrastructureID)
+
+	targetStatus := DEPLOY_STATUS_FINISHED
+	if operation.InfrastructureDeployType == DEPLOY_TYPE_DELETE {
+		targetStatus = DEPLOY_STATUS_DELETED
+	}
+
+	return operation.InfrastructureDeployType, waitForInfrastructureFinished(infrastructureID, ctx, d, meta, timeout, targetStatus)
+}
+
 //deployInfrastructure starts a deploy
 func deployInfrastructure(infrastructureID int, d *schema.ResourceData, meta interface{}) error {
 	client := meta.(*mc.Client)
@@ -451,6 +563,7 @@ const DEPLOY_STATUS_FINISHED = "finished"
 const DEPLOY_STATUS_ONGOING = "ongoing"
 const DEPLOY_STATUS_DELETED = "deleted"
 const DEPLOY_STATUS_NOT_STARTED = "not_started"
+const DEPLOY_TYPE_DELETE = "delete"
 const NETWORK_TYPE_LAN = "lan"
 const NETWORK_TYPE_SAN = "san"
 const NETWORK_TYPE_WAN = "wan"
@@ -2,12 +2,15 @@ package metalcloud
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
@@ -22,6 +25,7 @@ func resourceInstanceArray() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		CustomizeDiff: resourceInstanceArrayCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			"infrastructure_id": &schema.Schema{
 				Type:     schema.TypeInt,
@@ -65,7 +69,14 @@ func resourceInstanceArray() *schema.Resource {
 			"instance_array_boot_method": &schema.Schema{
 				Type:     schema.TypeString,
 				Optional: true,
-				Default:  "pxe_iscsi",
+				Default:  PXE_ISCSI,
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					v := val.(string)
+					if v != PXE_ISCSI && v != LOCAL_DRIVES {
+						errs = append(errs, fmt.Errorf("%q must be one of '%s', '%s'. Provided value: %s", key, PXE_ISCSI, LOCAL_DRIVES, v))
+					}
+					return
+				},
 			},
 			"instance_array_ram_gbytes": &schema.Schema{
 				Type:     schema.TypeInt,
@@ -104,8 +115,17 @@ func resourceInstanceArray() *schema.Resource {
 				Computed: true, //default is computed serverside
 			},
 			"instance_array_additional_wan_ipv4_json": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				ConflictsWith:    []string{"additional_wan_ipv4"},
+				ValidateFunc:     validateAdditionalWanIPv4JSON,
+				DiffSuppressFunc: suppressEquivalentJSON,
+			},
+			"additional_wan_ipv4": {
+				Type:          schema.TypeList,
+				Elem:          resourceAdditionalWanIPv4(),
+				Optional:      true,
+				ConflictsWith: []string{"instance_array_additional_wan_ipv4_json"},
 			},
 			"instance_array_custom_variables": {
 				Type:     schema.TypeMap,
@@ -117,11 +137,72 @@ func resourceInstanceArray() *schema.Resource {
 				Elem:     instanceCustomVariableResource(),
 				Optional: true,
 			},
+			"instance_array_user_data": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"instance_array_user_data_encoding": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      USER_DATA_ENCODING_PLAIN,
+				ValidateFunc: validateUserDataEncoding,
+			},
+			"instance_array_user_data_path": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      USER_DATA_DEFAULT_PATH,
+				ValidateFunc: validateUserDataPath,
+			},
+			"user_data_os_asset_id": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"instance_user_data_os_asset_ids": {
+				Type:     schema.TypeMap,
+				Elem:     &schema.Schema{Type: schema.TypeInt},
+				Computed: true,
+			},
+			"ssh_key_ids": {
+				Type:     schema.TypeList,
+				Elem:     &schema.Schema{Type: schema.TypeInt},
+				Optional: true,
+			},
+			"instance_array_ssh_keys_path": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      SSH_KEYS_DEFAULT_PATH,
+				ValidateFunc: validateUserDataPath,
+			},
+			"ssh_keys_os_asset_id": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"user_data_reinstall_required": {
+				Type:     schema.TypeList,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Computed: true,
+			},
+			"hardware_swap_required": {
+				Type:     schema.TypeList,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Computed: true,
+			},
 			"volume_template_id": &schema.Schema{
 				Type:     schema.TypeInt,
 				Optional: true,
 				Default:  0,
 			},
+			"custom_iso_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Default:  0,
+			},
+			"custom_iso_booted_instances": {
+				Type:     schema.TypeList,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Computed: true,
+			},
 			"instance_array_firewall_managed": {
 				Type:     schema.TypeBool,
 				Optional: true,
@@ -134,6 +215,16 @@ func resourceInstanceArray() *schema.Resource {
 				Computed: true, //default is computed serverside
 				Elem:     resourceFirewallRule(),
 			},
+			"firewall_rule_set_ids": {
+				Type:     schema.TypeList,
+				Elem:     &schema.Schema{Type: schema.TypeInt},
+				Optional: true,
+			},
+			"allow_redundant_firewall_rules": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
 			"interface": {
 				Type:     schema.TypeSet,
 				Optional: true,
@@ -153,21 +244,72 @@ func resourceInstanceArray() *schema.Resource {
 				Elem:     instanceServerTypeResource(),
 				Optional: true,
 			},
+			"instance_index_mapping": {
+				Type:     schema.TypeMap,
+				Elem:     &schema.Schema{Type: schema.TypeInt},
+				Computed: true,
+			},
+			"instances": {
+				Type:     schema.TypeList,
+				Elem:     resourceInstanceArrayInstance(),
+				Computed: true,
+			},
 			"drive_array_id_boot": {
 				Type:     schema.TypeInt,
 				Optional: true,
 				Default:  0,
 			},
+			"swap_existing_instances_hardware": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"keep_detaching_drives": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"instances_to_delete": {
+				Type:     schema.TypeSet,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Optional: true,
+			},
+			"require_explicit_scale_down": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
 		},
 	}
 }
 
+//instanceArrayHardwareFields are the properties that select the hardware of the instances.
+//Changing them only affects existing instances when swap_existing_instances_hardware is set.
+var instanceArrayHardwareFields = []string{
+	"instance_array_ram_gbytes",
+	"instance_array_processor_count",
+	"instance_array_processor_core_mhz",
+	"instance_array_processor_core_count",
+	"instance_array_disk_count",
+	"instance_array_disk_size_mbytes",
+}
+
 func resourceInstanceArrayNetworkProfile() *schema.Resource {
 	return &schema.Resource{
 		Schema: map[string]*schema.Schema{
 			"network_id": {
 				Type:     schema.TypeInt,
-				Required: true,
+				Optional: true,
+				Computed: true,
+			},
+			"network_label": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"network_type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateNetworkType,
 			},
 			"network_profile_id": {
 				Type:     schema.TypeInt,
@@ -182,12 +324,32 @@ func instanceCustomVariableResource() *schema.Resource {
 		Schema: map[string]*schema.Schema{
 			"instance_index": &schema.Schema{
 				Type:     schema.TypeInt,
-				Required: true,
+				Optional: true,
+				Default:  -1,
+			},
+			"instance_label": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"instance_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
 			},
 			"custom_variables": &schema.Schema{
 				Type:     schema.TypeMap,
 				Elem:     schema.TypeString,
-				Required: true,
+				Optional: true,
+			},
+			"user_data": &schema.Schema{
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"user_data_encoding": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      USER_DATA_ENCODING_PLAIN,
+				ValidateFunc: validateUserDataEncoding,
 			},
 		},
 	}
@@ -198,7 +360,16 @@ func instanceServerTypeResource() *schema.Resource {
 		Schema: map[string]*schema.Schema{
 			"instance_index": &schema.Schema{
 				Type:     schema.TypeInt,
-				Required: true,
+				Optional: true,
+				Default:  -1,
+			},
+			"instance_label": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"instance_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
 			},
 			"server_type_id": &schema.Schema{
 				Type:     schema.TypeInt,
@@ -245,6 +416,16 @@ func resourceFirewallRule() *schema.Resource {
 				Optional: true,
 				Default:  nil,
 			},
+			"source_cidr": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateCIDR,
+			},
+			"destination_cidr": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateCIDR,
+			},
 			"firewall_rule_protocol": &schema.Schema{
 				Type:     schema.TypeString,
 				Optional: true,
@@ -264,6 +445,35 @@ func resourceFirewallRule() *schema.Resource {
 	}
 }
 
+func resourceAdditionalWanIPv4() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"forced_subnet_pool_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Required: true,
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					if val.(int) <= 0 {
+						errs = append(errs, fmt.Errorf("%q must be the id of a subnet pool, got: %d", key, val.(int)))
+					}
+					return
+				},
+			},
+			"override_vlan_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Default:  0,
+				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+					v := val.(int)
+					if v < 0 || v > 4094 {
+						errs = append(errs, fmt.Errorf("%q must be between 1 and 4094, or 0 to use the default VLAN, got: %d", key, v))
+					}
+					return
+				},
+			},
+		},
+	}
+}
+
 func resourceInstanceArrayInterface() *schema.Resource {
 	return &schema.Resource{
 		Schema: map[string]*schema.Schema{
@@ -273,154 +483,975 @@ func resourceInstanceArrayInterface() *schema.Resource {
 			},
 			"network_id": {
 				Type:     schema.TypeInt,
-				Required: true,
+				Optional: true,
+				Computed: true,
+			},
+			"network_label": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"network_type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateNetworkType,
+			},
+			"lagg_indexes": {
+				Type:     schema.TypeSet,
+				Elem:     &schema.Schema{Type: schema.TypeInt},
+				Set:      schema.HashInt,
+				Optional: true,
 			},
 		},
 	}
 }
 
-func resourceInstanceArrayCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*mc.Client)
-
-	var diags diag.Diagnostics
-
-	infrastructure_id := d.Get("infrastructure_id").(int)
-	_, err := client.InfrastructureGet(infrastructure_id)
-
-	if err != nil {
-		return diag.Errorf("Infrastructure with id %+v not found.", infrastructure_id)
+//resourceInstanceArrayInstance describes a deployed instance of the instance array and the IPs allocated on its interfaces.
+func resourceInstanceArrayInstance() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"instance_id": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"instance_label": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"instance_subdomain": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"instance_subdomain_permanent": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"server_id": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"interface": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"interface_index": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"network_id": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"ipv4": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     resourceInstanceIP(),
+						},
+						"ipv6": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     resourceInstanceIP(),
+						},
+					},
+				},
+			},
+		},
 	}
-	ia := expandInstanceArray(d)
+}
 
-	if ia.VolumeTemplateID != 0 && ia.DriveArrayIDBoot == 0 && ia.InstanceArrayBootMethod != LOCAL_DRIVES {
-		return diag.Errorf("Current instance array configuration is only valid for %s boot method", LOCAL_DRIVES)
+func resourceInstanceIP() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"ip_address": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"gateway": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"netmask": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"subnet_destination": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
 	}
+}
 
-	if ia.DriveArrayIDBoot != 0 && ia.InstanceArrayBootMethod != PXE_ISCSI {
-		return diag.Errorf("Current instance array configuration is only valid for %s boot method", PXE_ISCSI)
+//resourceInstanceArrayCustomizeDiff validates the instance array at plan time so that invalid configurations
+//are rejected before any other resource is changed.
+func resourceInstanceArrayCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if err := validateInstanceArrayBootMethod(d, meta); err != nil {
+		return err
 	}
 
-	iaC, err := client.InstanceArrayCreate(infrastructure_id, ia)
-	if err != nil {
-		return diag.FromErr(err)
+	if err := validateInstanceArrayFirewallRules(d, meta); err != nil {
+		return err
 	}
 
-	id := fmt.Sprintf("%d", iaC.InstanceArrayID)
-
-	d.SetId(id)
-
-	/* custom variables for instances */
-	cvList := d.Get("instance_custom_variables").([]interface{})
-	dg := updateInstancesCustomVariables(cvList, iaC.InstanceArrayID, client)
-
-	if dg.HasError() {
-		resourceInstanceArrayRead(ctx, d, meta)
-		return dg
+	if err := resolveInstanceArrayNetworks(d, meta); err != nil {
+		return err
 	}
 
-	diags = append(diags, dg...)
-
-	/* update server types */
-	iList := d.Get("instance_server_type").([]interface{})
-	dg = updateInstancesServerTypes(iList, iaC.InstanceArrayID, client)
-
-	if dg.HasError() {
-		resourceInstanceArrayRead(ctx, d, meta)
-		return dg
+	if err := validateInstanceArrayInterfaceLAGG(d); err != nil {
+		return err
 	}
 
-	diags = append(diags, dg...)
+	if err := diffInstanceArrayHardwareSwap(d, meta); err != nil {
+		return err
+	}
 
-	for _, intf := range ia.InstanceArrayInterfaces {
-		_, err := client.InstanceArrayInterfaceAttachNetwork(iaC.InstanceArrayID, intf.InstanceArrayInterfaceIndex, intf.NetworkID)
-		if err != nil {
-			resourceInstanceArrayRead(ctx, d, meta)
-			return diag.FromErr(err)
-		}
+	if err := validateInstanceArrayScaleDown(d, meta); err != nil {
+		return err
 	}
 
-	if d.Get("network_profile") != nil {
-		profileSet := d.Get("network_profile").(*schema.Set)
+	if err := validateInstanceArrayUserData(d); err != nil {
+		return err
+	}
 
-		for _, profileIntf := range profileSet.List() {
-			profileMap := profileIntf.(map[string]interface{})
+	if err := validateInstanceArraySSHKeys(d, meta); err != nil {
+		return err
+	}
 
-			_, err := client.InstanceArrayNetworkProfileSet(iaC.InstanceArrayID, profileMap["network_id"].(int), profileMap["network_profile_id"].(int))
+	if err := diffInstanceIndexMapping(d, meta); err != nil {
+		return err
+	}
 
-			if err != nil {
-				resourceInstanceArrayRead(ctx, d, meta)
-				return diag.FromErr(err)
-			}
-		}
+	if err := diffInstanceArrayUserData(d, meta); err != nil {
+		return err
 	}
 
-	dg = resourceInstanceArrayRead(ctx, d, meta)
-	diags = append(diags, dg...)
+	if err := diffInstanceArrayCustomISO(d); err != nil {
+		return err
+	}
 
-	return diags
+	return diffInstanceArrayInstances(d)
 }
 
-func resourceInstanceArrayRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+//diffInstanceArrayInstances marks the instances as known after apply when the changes add, remove or reconnect instances
+func diffInstanceArrayInstances(d *schema.ResourceDiff) error {
+	if d.Id() == "" {
+		return nil
+	}
 
-	var diags diag.Diagnostics
+	if d.HasChange("instance_array_instance_count") || d.HasChange("interface") || d.HasChange("network_profile") {
+		return d.SetNewComputed("instances")
+	}
 
-	client := meta.(*mc.Client)
+	return nil
+}
 
-	id, err := strconv.Atoi(d.Id())
-	if err != nil {
-		return diag.FromErr(err)
+//validateInstanceArrayFirewallRules validates the configured firewall rules together with the rules of the firewall rule sets.
+//Rules that are not configured are computed server side and are not checked.
+func validateInstanceArrayFirewallRules(d *schema.ResourceDiff, meta interface{}) error {
+	if !(d.HasChange("firewall_rule") || d.HasChange("firewall_rule_set_ids")) || !d.NewValueKnown("firewall_rule") {
+		return nil
 	}
 
-	ia, err := client.InstanceArrayGet(id)
-	if err != nil {
-		return diag.FromErr(err)
+	rules := []mc.FirewallRule{}
+
+	for _, fwMap := range d.Get("firewall_rule").(*schema.Set).List() {
+		fw, err := expandFirewallRuleMap(fwMap.(map[string]interface{}))
+		if err != nil {
+			return fmt.Errorf("firewall_rule: %s", err)
+		}
+		rules = append(rules, fw)
 	}
 
-	flattenInstanceArray(d, *ia)
+	//the rules of firewall rule sets created or updated in the same apply are checked by the set
+	if configuredListKnown(d, "firewall_rule_set_ids") {
+		setRules, err := firewallRuleSetsRules(d.Get("firewall_rule_set_ids").([]interface{}), meta.(*mc.Client))
+		if err != nil {
+			return err
+		}
 
-	/* INSTANCES */
-	retInstances, err := client.InstanceArrayInstances(ia.InstanceArrayID)
-	if err != nil {
-		return diag.FromErr(err)
+		rules = append(rules, uniqueFirewallRules(setRules)...)
 	}
 
-	networkProfiles, err := client.NetworkProfileListByInstanceArray(ia.InstanceArrayID)
-
+	warnings, err := validateFirewallRules(rules)
 	if err != nil {
-		return diag.FromErr(err)
+		return err
 	}
 
-	profiles := flattenInstanceArrayNetworkProfile(*networkProfiles, d)
+	return checkFirewallRuleWarnings(warnings, d.Get("allow_redundant_firewall_rules").(bool))
+}
 
-	if len(profiles) > 0 {
-		d.Set("network_profile", schema.NewSet(schema.HashResource(resourceInstanceArrayNetworkProfile()), profiles))
-	}
+//resolveInstanceArrayNetworks sets the network_id of the interface and network_profile blocks that use network_label
+//or network_type so that the plan shows the network they will be connected to. Networks that do not exist yet are
+//resolved when the plan is refreshed during apply.
+func resolveInstanceArrayNetworks(d *schema.ResourceDiff, meta interface{}) error {
+	var networks *map[string]mc.Network
 
-	//instances
-	keys := []int{}
-	instances := []mc.Instance{}
+	for _, key := range []string{"interface", "network_profile"} {
+		if !d.NewValueKnown(key) {
+			continue
+		}
 
-	for _, v := range *retInstances {
+		blocks := d.Get(key).(*schema.Set).List()
 
-		keys = append(keys, v.InstanceID)
-	}
+		if !hasNetworkReference(blocks) {
+			continue
+		}
 
-	sort.Ints(keys)
+		if !d.NewValueKnown("infrastructure_id") {
+			if err := d.SetNewComputed(key); err != nil {
+				return err
+			}
+			continue
+		}
 
-	for _, id := range keys {
-		i, err := client.InstanceGet(id)
+		if networks == nil {
+			var err error
+			networks, err = meta.(*mc.Client).Networks(d.Get("infrastructure_id").(int))
+			if err != nil {
+				return err
+			}
+		}
+
+		resolved := true
+
+		for _, bIntf := range blocks {
+			block := bIntf.(map[string]interface{})
+
+			networkID, err := resolveNetworkReference(block, *networks)
+			if err != nil {
+				return fmt.Errorf("%s: %s", key, err)
+			}
+
+			if networkID == 0 {
+				resolved = false
+				log.Printf("[WARN] %s: network %s%s not found in infrastructure %d, it will be resolved on apply", key, block["network_label"], block["network_type"], d.Get("infrastructure_id").(int))
+				break
+			}
+
+			block["network_id"] = networkID
+		}
+
+		if !resolved {
+			if err := d.SetNewComputed(key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := d.SetNew(key, blocks); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hasNetworkReference(blocks []interface{}) bool {
+	for _, bIntf := range blocks {
+		block := bIntf.(map[string]interface{})
+		if block["network_label"].(string) != "" || block["network_type"].(string) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+//resolveNetworkReference returns the id of the network referenced by network_label or network_type, the configured
+//network_id if neither is set, or 0 if the network does not exist.
+func resolveNetworkReference(block map[string]interface{}, networks map[string]mc.Network) (int, error) {
+	label := block["network_label"].(string)
+	networkType := block["network_type"].(string)
+
+	if label != "" && networkType != "" {
+		return 0, fmt.Errorf("only one of network_label or network_type can be set, got %s and %s", label, networkType)
+	}
+
+	if label == "" && networkType == "" {
+		return block["network_id"].(int), nil
+	}
+
+	found := []mc.Network{}
+
+	for _, n := range networks {
+		if (label != "" && strings.EqualFold(n.NetworkLabel, label)) || (networkType != "" && n.NetworkType == networkType) {
+			found = append(found, n)
+		}
+	}
+
+	if len(found) > 1 {
+		return 0, fmt.Errorf("network_type %s matches %d networks, use network_label instead", networkType, len(found))
+	}
+
+	if len(found) == 0 {
+		return 0, nil
+	}
+
+	return found[0].NetworkID, nil
+}
+
+//validateResolvedNetworks fails if a network_label or network_type could not be resolved during plan
+func validateResolvedNetworks(d *schema.ResourceData) error {
+	for _, key := range []string{"interface", "network_profile"} {
+		for _, bIntf := range d.Get(key).(*schema.Set).List() {
+			block := bIntf.(map[string]interface{})
+
+			if block["network_id"].(int) == 0 {
+				return fmt.Errorf("%s: network %s%s was not found in infrastructure %d. Make sure the network exists or add a depends_on to the resource that creates it", key, block["network_label"], block["network_type"], d.Get("infrastructure_id").(int))
+			}
+		}
+	}
+
+	return nil
+}
+
+//validateInstanceArrayInterfaceLAGG checks that the interfaces listed in lagg_indexes are configured and are
+//connected to the same network as the interface they are bonded with, and that existing bonds are not emptied.
+func validateInstanceArrayInterfaceLAGG(d *schema.ResourceDiff) error {
+	if !d.HasChange("interface") || !d.NewValueKnown("interface") {
+		return nil
+	}
+
+	interfaces := make(map[int]map[string]interface{})
+
+	for _, iIntf := range d.Get("interface").(*schema.Set).List() {
+		intf := iIntf.(map[string]interface{})
+		interfaces[intf["interface_index"].(int)] = intf
+	}
+
+	//the SDK omits empty lagg_indexes when editing the interface so the last bond member cannot be removed
+	o, _ := d.GetChange("interface")
+	for _, iIntf := range o.(*schema.Set).List() {
+		old := iIntf.(map[string]interface{})
+		oldLagg, _ := old["lagg_indexes"].(*schema.Set)
+
+		intf, ok := interfaces[old["interface_index"].(int)]
+		if !ok || oldLagg == nil || oldLagg.Len() == 0 {
+			continue
+		}
+
+		if newLagg, _ := intf["lagg_indexes"].(*schema.Set); newLagg == nil || newLagg.Len() == 0 {
+			return fmt.Errorf("interface %d: lagg_indexes cannot be emptied, the API client does not send empty lists so the interface would stay bonded. Remove the interface blocks of the bond to detach its interfaces instead", old["interface_index"].(int))
+		}
+	}
+
+	for index, intf := range interfaces {
+		laggIndexes, ok := intf["lagg_indexes"].(*schema.Set)
+		if !ok {
+			continue
+		}
+
+		for _, lIntf := range laggIndexes.List() {
+			laggIndex := lIntf.(int)
+
+			if laggIndex == index {
+				return fmt.Errorf("interface %d: lagg_indexes cannot contain the interface's own index", index)
+			}
+
+			bonded, ok := interfaces[laggIndex]
+			if !ok {
+				return fmt.Errorf("interface %d: lagg_indexes contains interface %d which is not configured. Add an interface block for it connected to the same network", index, laggIndex)
+			}
+
+			if bonded["network_id"].(int) != intf["network_id"].(int) {
+				return fmt.Errorf("interface %d is bonded with interface %d but they are connected to different networks (%d and %d). Bonded interfaces must share a network", index, laggIndex, intf["network_id"].(int), bonded["network_id"].(int))
+			}
+		}
+	}
+
+	return nil
+}
+
+//validateInstanceArrayScaleDown checks that instances_to_delete names as many existing instances as the
+//instance count is reduced by and, with require_explicit_scale_down, that it is set for every reduction.
+func validateInstanceArrayScaleDown(d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" || !d.NewValueKnown("instance_array_instance_count") || !d.NewValueKnown("instances_to_delete") {
+		return nil
+	}
+
+	o, n := d.GetChange("instance_array_instance_count")
+	removed := o.(int) - n.(int)
+
+	if removed <= 0 {
+		return nil
+	}
+
+	toDelete := d.Get("instances_to_delete").(*schema.Set).List()
+	ids := []int{}
+
+	if len(toDelete) > 0 {
+		id, err := strconv.Atoi(d.Id())
+		if err != nil {
+			return err
+		}
+
+		client := meta.(*mc.Client)
+
+		instanceList, err := client.InstanceArrayInstances(id)
 		if err != nil {
+			return err
+		}
+
+		previous, _ := d.GetChange("instances_to_delete")
+
+		ids, err = expandInstancesToDelete(toDelete, previous.(*schema.Set).List(), *instanceList)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(ids) == 0 {
+		if d.Get("require_explicit_scale_down").(bool) {
+			return fmt.Errorf("instance_array_instance_count: reducing the instance count from %d to %d requires instances_to_delete to list the %d instances to remove because require_explicit_scale_down is set", o.(int), n.(int), removed)
+		}
+		return nil
+	}
+
+	if len(ids) != removed {
+		return fmt.Errorf("instances_to_delete: %d instances are listed but instance_array_instance_count is reduced by %d", len(ids), removed)
+	}
+
+	return nil
+}
+
+//diffInstanceIndexMapping validates the instance addressing of the instance_custom_variables and instance_server_type
+//blocks and records in the plan any instance_index that would now point to a different instance.
+func diffInstanceIndexMapping(d *schema.ResourceDiff, meta interface{}) error {
+	//the instance addresses are validated again at apply when they depend on values not known yet
+	for _, key := range []string{"instance_custom_variables", "instance_server_type"} {
+		if !configuredBlocksKnown(d, key, "instance_index", "instance_label", "instance_id") {
+			return d.SetNewComputed("instance_index_mapping")
+		}
+	}
+
+	blocks := instanceAddressedBlocks(d.Get("instance_custom_variables").([]interface{}), d.Get("instance_server_type").([]interface{}))
+
+	for _, b := range blocks {
+		if err := validateInstanceAddress(b.(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+
+	oldMapping, _ := d.GetChange("instance_index_mapping")
+
+	//the instances are not known before the instance array is created
+	if d.Id() == "" {
+		if hasInstanceIndexAddress(blocks) {
+			return d.SetNewComputed("instance_index_mapping")
+		}
+		return nil
+	}
+
+	if !hasInstanceIndexAddress(blocks) && len(oldMapping.(map[string]interface{})) == 0 {
+		return nil
+	}
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return err
+	}
+
+	client := meta.(*mc.Client)
+
+	instanceList, err := client.InstanceArrayInstances(id)
+	if err != nil {
+		return err
+	}
+
+	instances := sortInstances(*instanceList)
+	scaled := d.HasChange("instance_array_instance_count")
+
+	if scaled {
+		surviving, known, err := survivingInstances(d, *instanceList)
+		if err != nil {
+			return err
+		}
+
+		if !known {
+			return d.SetNewComputed("instance_index_mapping")
+		}
+
+		instances = surviving
+	}
+
+	mapping := instanceIndexMapping(blocks, instances, oldMapping.(map[string]interface{}))
+
+	for _, b := range blocks {
+		if _, err := resolveInstanceAddress(b.(map[string]interface{}), instances, mapping); err != nil {
+			//the instances added by a scale up are only known after apply
+			if o, n := d.GetChange("instance_array_instance_count"); scaled && n.(int) > o.(int) {
+				return d.SetNewComputed("instance_index_mapping")
+			}
+			return err
+		}
+	}
+
+	if !reflect.DeepEqual(mapping, oldMapping.(map[string]interface{})) {
+		return d.SetNew("instance_index_mapping", mapping)
+	}
+
+	return nil
+}
+
+//survivingInstances returns the instances that remain after the instance count changes. When scaling down they are
+//only known if instances_to_delete lists the instances to remove, otherwise they are chosen server side.
+func survivingInstances(d *schema.ResourceDiff, instances map[string]mc.Instance) ([]mc.Instance, bool, error) {
+	o, n := d.GetChange("instance_array_instance_count")
+
+	//added instances get higher ids so the existing instances keep their positions
+	if n.(int) > o.(int) {
+		return sortInstances(instances), true, nil
+	}
+
+	if !d.NewValueKnown("instances_to_delete") {
+		return nil, false, nil
+	}
+
+	previous, _ := d.GetChange("instances_to_delete")
+
+	ids, err := expandInstancesToDelete(d.Get("instances_to_delete").(*schema.Set).List(), previous.(*schema.Set).List(), instances)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(ids) == 0 {
+		return nil, false, nil
+	}
+
+	deleted := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		deleted[id] = true
+	}
+
+	surviving := []mc.Instance{}
+	for _, instance := range sortInstances(instances) {
+		if !deleted[instance.InstanceID] {
+			surviving = append(surviving, instance)
+		}
+	}
+
+	return surviving, true, nil
+}
+
+//diffInstanceArrayHardwareSwap shows in hardware_swap_required the deployed instances whose server no longer matches
+//the configured hardware. With swap_existing_instances_hardware they are moved to other servers on the next deploy,
+//otherwise they keep their current servers. The SDK does not allow warnings on plan so the list is also logged and
+//returned as a warning on apply.
+func diffInstanceArrayHardwareSwap(d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" {
+		return nil
+	}
+
+	changed := false
+	for _, f := range instanceArrayHardwareFields {
+		if !d.NewValueKnown(f) {
+			return d.SetNewComputed("hardware_swap_required")
+		}
+
+		if d.HasChange(f) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return err
+	}
+
+	client := meta.(*mc.Client)
+
+	instanceList, err := client.InstanceArrayInstances(id)
+	if err != nil {
+		return err
+	}
+
+	hw := mc.HardwareConfiguration{
+		InstanceArrayRAMGbytes:          d.Get("instance_array_ram_gbytes").(int),
+		InstanceArrayProcessorCount:     d.Get("instance_array_processor_count").(int),
+		InstanceArrayProcessorCoreMHZ:   d.Get("instance_array_processor_core_mhz").(int),
+		InstanceArrayProcessorCoreCount: d.Get("instance_array_processor_core_count").(int),
+		InstanceArrayDiskCount:          d.Get("instance_array_disk_count").(int),
+		InstanceArrayDiskSizeMBytes:     d.Get("instance_array_disk_size_mbytes").(int),
+	}
+
+	labels, err := hardwareSwapRequired(sortInstances(*instanceList), hw, client)
+	if err != nil {
+		return err
+	}
+
+	if len(labels) > 0 {
+		log.Printf("[WARN] %s", instanceArrayHardwareSwapMessage(d.Get("instance_array_label").(string), labels, d.Get("swap_existing_instances_hardware").(bool)))
+	}
+
+	oList, _ := d.GetChange("hardware_swap_required")
+
+	if reflect.DeepEqual(labels, expandStringList(oList.([]interface{}))) {
+		return nil
+	}
+
+	return d.SetNew("hardware_swap_required", labels)
+}
+
+//hardwareSwapRequired returns the labels of the deployed instances whose server type does not match the hardware configuration.
+//The server types are retrieved with a single call, only if there are deployed instances.
+func hardwareSwapRequired(instances []mc.Instance, hw mc.HardwareConfiguration, client *mc.Client) ([]string, error) {
+	labels := []string{}
+
+	var serverTypes *map[int]mc.ServerType
+
+	for _, i := range instances {
+		if i.ServerID == 0 || i.ServerTypeID == 0 || i.InstanceOperation.InstanceDeployType == DEPLOY_TYPE_DELETE {
+			continue
+		}
+
+		if serverTypes == nil {
+			retServerTypes, err := client.ServerTypes(false)
+			if err != nil {
+				return nil, err
+			}
+
+			serverTypes = retServerTypes
+		}
+
+		st, ok := (*serverTypes)[i.ServerTypeID]
+		if !ok {
+			return nil, fmt.Errorf("server type %d of instance %s was not found", i.ServerTypeID, i.InstanceLabel)
+		}
+
+		if !serverTypeMatchesHardware(st, hw) {
+			labels = append(labels, i.InstanceLabel)
+		}
+	}
+
+	sort.Strings(labels)
+
+	return labels, nil
+}
+
+//expandHardwareConfiguration returns the hardware configuration of a saved instance array
+func expandHardwareConfiguration(iao mc.InstanceArrayOperation) mc.HardwareConfiguration {
+	return mc.HardwareConfiguration{
+		InstanceArrayRAMGbytes:          iao.InstanceArrayRAMGbytes,
+		InstanceArrayProcessorCount:     iao.InstanceArrayProcessorCount,
+		InstanceArrayProcessorCoreMHZ:   iao.InstanceArrayProcessorCoreMHZ,
+		InstanceArrayProcessorCoreCount: iao.InstanceArrayProcessorCoreCount,
+		InstanceArrayDiskCount:          iao.InstanceArrayDiskCount,
+		InstanceArrayDiskSizeMBytes:     iao.InstanceArrayDiskSizeMBytes,
+		InstanceArrayDiskTypes:          iao.InstanceArrayDiskTypes,
+	}
+}
+
+//serverTypeMatchesHardware checks that the server type has at least the resources of the hardware configuration
+func serverTypeMatchesHardware(st mc.ServerType, hw mc.HardwareConfiguration) bool {
+	return st.ServerRAMGbytes >= hw.InstanceArrayRAMGbytes &&
+		st.ServerProcessorCount >= hw.InstanceArrayProcessorCount &&
+		st.ServerProcessorCoreMHz >= hw.InstanceArrayProcessorCoreMHZ &&
+		st.ServerProcessorCoreCount >= hw.InstanceArrayProcessorCoreCount &&
+		st.ServerDiskCount >= hw.InstanceArrayDiskCount &&
+		st.ServerDiskSizeMBytes >= hw.InstanceArrayDiskSizeMBytes
+}
+
+func instanceArrayHardwareSwapMessage(label string, instances []string, swap bool) string {
+	if swap {
+		return fmt.Sprintf("Instance array %s: the servers of the instances %s do not match the hardware configuration. They will be moved to other servers on the next deploy.", label, strings.Join(instances, ", "))
+	}
+
+	return fmt.Sprintf("Instance array %s: the servers of the instances %s do not match the hardware configuration but swap_existing_instances_hardware is not set. They keep their current servers, only new instances use matching servers.", label, strings.Join(instances, ", "))
+}
+
+//validateInstanceArrayBootMethod checks that volume_template_id, drive_array_id_boot and instance_array_boot_method
+//are compatible and that the volume template supports the boot method. Values not known at plan time are skipped
+//and checked by checkInstanceArrayBoot during apply.
+func validateInstanceArrayBootMethod(d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("instance_array_boot_method") || !d.NewValueKnown("volume_template_id") || !d.NewValueKnown("drive_array_id_boot") || !d.NewValueKnown("custom_iso_id") {
+		return nil
+	}
+
+	bootMethod := d.Get("instance_array_boot_method").(string)
+	volumeTemplateID := d.Get("volume_template_id").(int)
+	customISOID := d.Get("custom_iso_id").(int)
+
+	if err := checkInstanceArrayBootMethod(bootMethod, volumeTemplateID, d.Get("drive_array_id_boot").(int), customISOID); err != nil {
+		return err
+	}
+
+	if customISOID != 0 && d.HasChange("custom_iso_id") {
+		if err := checkCustomISO(customISOID, meta); err != nil {
+			return err
+		}
+	}
+
+	if volumeTemplateID == 0 || !(d.HasChange("volume_template_id") || d.HasChange("instance_array_boot_method")) {
+		return nil
+	}
+
+	return checkVolumeTemplateBootMethod(volumeTemplateID, bootMethod, meta.(*mc.Client))
+}
+
+//checkInstanceArrayBoot checks the boot configuration during apply, when all the values are known.
+//The errors are reported on the attribute they are about.
+func checkInstanceArrayBoot(d *schema.ResourceData, ia mc.InstanceArray, client *mc.Client) diag.Diagnostics {
+	err := checkInstanceArrayBootMethod(ia.InstanceArrayBootMethod, ia.VolumeTemplateID, ia.DriveArrayIDBoot, d.Get("custom_iso_id").(int))
+
+	if err == nil && ia.VolumeTemplateID != 0 && (d.IsNewResource() || d.HasChange("volume_template_id") || d.HasChange("instance_array_boot_method")) {
+		err = checkVolumeTemplateBootMethod(ia.VolumeTemplateID, ia.InstanceArrayBootMethod, client)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if bErr, ok := err.(bootError); ok {
+		return diag.Diagnostics{
+			diag.Diagnostic{
+				Severity:      diag.Error,
+				Summary:       bErr.message,
+				AttributePath: cty.GetAttrPath(bErr.attribute),
+			},
+		}
+	}
+
+	return diag.FromErr(err)
+}
+
+//bootError is an error of the boot configuration and the attribute it is about
+type bootError struct {
+	attribute string
+	message   string
+}
+
+func (e bootError) Error() string {
+	return e.attribute + ": " + e.message
+}
+
+//checkInstanceArrayBootMethod checks that volume_template_id, drive_array_id_boot, custom_iso_id and instance_array_boot_method are compatible
+func checkInstanceArrayBootMethod(bootMethod string, volumeTemplateID int, driveArrayIDBoot int, customISOID int) error {
+	if customISOID != 0 && volumeTemplateID != 0 {
+		return bootError{"custom_iso_id", fmt.Sprintf("booting from custom_iso_id %d conflicts with volume_template_id %d, the OS is installed from the ISO", customISOID, volumeTemplateID)}
+	}
+
+	if customISOID != 0 && driveArrayIDBoot != 0 {
+		return bootError{"custom_iso_id", fmt.Sprintf("booting from custom_iso_id %d conflicts with drive_array_id_boot %d", customISOID, driveArrayIDBoot)}
+	}
+
+	if customISOID != 0 && bootMethod != LOCAL_DRIVES {
+		return bootError{"instance_array_boot_method", fmt.Sprintf("booting from custom_iso_id %d is only valid with the '%s' boot method, got '%s'. The ISO installs the OS on the local drives of the servers", customISOID, LOCAL_DRIVES, bootMethod)}
+	}
+
+	if volumeTemplateID != 0 && driveArrayIDBoot == 0 && bootMethod != LOCAL_DRIVES {
+		return bootError{"instance_array_boot_method", fmt.Sprintf("installing volume_template_id %d on local drives is only valid with the '%s' boot method, got '%s'. Set drive_array_id_boot to boot from a drive array", volumeTemplateID, LOCAL_DRIVES, bootMethod)}
+	}
+
+	if driveArrayIDBoot != 0 && bootMethod != PXE_ISCSI {
+		return bootError{"instance_array_boot_method", fmt.Sprintf("booting from drive_array_id_boot %d is only valid with the '%s' boot method, got '%s'", driveArrayIDBoot, PXE_ISCSI, bootMethod)}
+	}
+
+	return nil
+}
+
+//checkVolumeTemplateBootMethod checks that the volume template supports the boot method
+func checkVolumeTemplateBootMethod(volumeTemplateID int, bootMethod string, client *mc.Client) error {
+	vt, err := client.VolumeTemplateGet(volumeTemplateID)
+	if err != nil {
+		return bootError{"volume_template_id", fmt.Sprintf("could not retrieve volume template %d: %s", volumeTemplateID, err)}
+	}
+
+	if vt.VolumeTemplateBootMethodsSupported == "" {
+		return nil
+	}
+
+	for _, supported := range volumeTemplateBootMethods(*vt) {
+		if supported == bootMethod {
+			return nil
+		}
+	}
+
+	return bootError{"volume_template_id", fmt.Sprintf("volume template %s (%d) does not support the '%s' boot method. Supported boot methods: %s", vt.VolumeTemplateLabel, volumeTemplateID, bootMethod, vt.VolumeTemplateBootMethodsSupported)}
+}
+
+func resourceInstanceArrayCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*mc.Client)
+
+	var diags diag.Diagnostics
+
+	infrastructure_id := d.Get("infrastructure_id").(int)
+	_, err := client.InfrastructureGet(infrastructure_id)
+
+	if err != nil {
+		return diag.Errorf("Infrastructure with id %+v not found.", infrastructure_id)
+	}
+	if err := validateResolvedNetworks(d); err != nil {
+		return diag.FromErr(err)
+	}
+
+	ia := expandInstanceArray(d)
+
+	if dg := checkInstanceArrayBoot(d, ia, client); dg.HasError() {
+		return dg
+	}
+
+	if err := addFirewallRuleSetsRules(d, &ia, client); err != nil {
+		return diag.FromErr(err)
+	}
+
+	diags = append(diags, firewallRuleDiagnostics(ia.InstanceArrayFirewallRules)...)
+
+	iaC, err := client.InstanceArrayCreate(infrastructure_id, ia)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	id := fmt.Sprintf("%d", iaC.InstanceArrayID)
+
+	d.SetId(id)
+
+	cvList := d.Get("instance_custom_variables").([]interface{})
+	iList := d.Get("instance_server_type").([]interface{})
+
+	mapping, dg := updateInstanceIndexMapping(cvList, iList, iaC.InstanceArrayID, map[string]interface{}{}, client)
+
+	if dg.HasError() {
+		resourceInstanceArrayRead(ctx, d, meta)
+		return dg
+	}
+
+	d.Set("instance_index_mapping", mapping)
+
+	/* custom variables for instances */
+	dg = updateInstancesCustomVariables(cvList, iaC.InstanceArrayID, mapping, client)
+
+	if dg.HasError() {
+		resourceInstanceArrayRead(ctx, d, meta)
+		return dg
+	}
+
+	diags = append(diags, dg...)
+
+	/* update server types */
+	dg = updateInstancesServerTypes(iList, iaC.InstanceArrayID, mapping, client)
+
+	if dg.HasError() {
+		resourceInstanceArrayRead(ctx, d, meta)
+		return dg
+	}
+
+	diags = append(diags, dg...)
+
+	/* user data */
+	dg = updateUserDataOSAssets(d, iaC.InstanceArrayID, mapping, client)
+
+	if dg.HasError() {
+		resourceInstanceArrayRead(ctx, d, meta)
+		return dg
+	}
+
+	/* ssh keys */
+	dg = updateSSHKeysOSAsset(d, iaC.InstanceArrayID, meta)
+
+	if dg.HasError() {
+		resourceInstanceArrayRead(ctx, d, meta)
+		return dg
+	}
+
+	for _, intf := range ia.InstanceArrayInterfaces {
+		_, err := client.InstanceArrayInterfaceAttachNetwork(iaC.InstanceArrayID, intf.InstanceArrayInterfaceIndex, intf.NetworkID)
+		if err != nil {
+			resourceInstanceArrayRead(ctx, d, meta)
 			return diag.FromErr(err)
 		}
-		instances = append(instances, *i)
 	}
 
+	if d.Get("network_profile") != nil {
+		profileSet := d.Get("network_profile").(*schema.Set)
+
+		for _, profileIntf := range profileSet.List() {
+			profileMap := profileIntf.(map[string]interface{})
+
+			_, err := client.InstanceArrayNetworkProfileSet(iaC.InstanceArrayID, profileMap["network_id"].(int), profileMap["network_profile_id"].(int))
+
+			if err != nil {
+				resourceInstanceArrayRead(ctx, d, meta)
+				return diag.FromErr(err)
+			}
+		}
+	}
+
+	dg = resourceInstanceArrayRead(ctx, d, meta)
+	diags = append(diags, dg...)
+
+	return diags
+}
+
+func resourceInstanceArrayRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+
+	var diags diag.Diagnostics
+
+	client := meta.(*mc.Client)
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	ia, err := client.InstanceArrayGet(id)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	//rules coming from firewall rule sets are not part of firewall_rule
+	if ids := d.Get("firewall_rule_set_ids").([]interface{}); len(ids) > 0 {
+		setRules, err := firewallRuleSetsRules(ids, client)
+		if err != nil {
+			log.Printf("[WARN] %s", err)
+		} else {
+			ia.InstanceArrayFirewallRules = excludeFirewallRules(ia.InstanceArrayFirewallRules, uniqueFirewallRules(setRules))
+		}
+	}
+
+	flattenInstanceArray(d, *ia)
+
+	/* INSTANCES */
+	retInstances, err := client.InstanceArrayInstances(ia.InstanceArrayID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	networkProfiles, err := client.NetworkProfileListByInstanceArray(ia.InstanceArrayID)
+
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	profiles := flattenInstanceArrayNetworkProfile(*networkProfiles, d)
+
+	if len(profiles) > 0 {
+		d.Set("network_profile", schema.NewSet(schema.HashResource(resourceInstanceArrayNetworkProfile()), profiles))
+	}
+
+	//instances, the list call returns the same objects as InstanceGet
+	instances := sortInstances(*retInstances)
+
 	d.Set("instance_array_instance_count", len(instances))
+	d.Set("instances", flattenInstances(instances))
+	d.Set("custom_iso_booted_instances", flattenCustomISOBootedInstances(d.Get("custom_iso_booted_instances").([]interface{}), instances))
+
+	if ia.InstanceArrayOperation != nil {
+		swap, err := hardwareSwapRequired(instances, expandHardwareConfiguration(*ia.InstanceArrayOperation), client)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		d.Set("hardware_swap_required", swap)
+	}
 
 	/* INSTANCES CUSTOM VARS */
-	instancesCustomVariables := flattenInstancesCustomVariables(retInstances)
+	instancesCustomVariables := flattenInstancesCustomVariables(retInstances, d.Get("instance_custom_variables").([]interface{}), d.Get("instance_index_mapping").(map[string]interface{}))
 
-	if len(instancesCustomVariables) > 0 {
+	if len(instancesCustomVariables) > 0 || len(d.Get("instance_custom_variables").([]interface{})) > 0 {
 		d.Set("instance_custom_variables", instancesCustomVariables)
 
 	}
@@ -444,21 +1475,31 @@ func resourceInstanceArrayUpdate(ctx context.Context, d *schema.ResourceData, me
 		return diag.FromErr(err)
 	}
 
+	if err := validateResolvedNetworks(d); err != nil {
+		return diag.FromErr(err)
+	}
+
 	ia := expandInstanceArray(d)
 
-	if ia.VolumeTemplateID != 0 && ia.DriveArrayIDBoot == 0 && ia.InstanceArrayBootMethod != LOCAL_DRIVES {
-		return diag.Errorf("Current instance array configuration is only valid for '%s' boot method", LOCAL_DRIVES)
+	if dg := checkInstanceArrayBoot(d, ia, client); dg.HasError() {
+		return dg
+	}
+
+	if err := addFirewallRuleSetsRules(d, &ia, client); err != nil {
+		return diag.FromErr(err)
 	}
 
-	if ia.DriveArrayIDBoot != 0 && ia.InstanceArrayBootMethod != PXE_ISCSI {
-		return diag.Errorf("Current instance array configuration is only valid for '%s' boot method", PXE_ISCSI)
+	if d.HasChange("firewall_rule") || d.HasChange("firewall_rule_set_ids") {
+		diags = append(diags, firewallRuleDiagnostics(ia.InstanceArrayFirewallRules)...)
 	}
 
 	//update interface operations
 	for _, intf := range ia.InstanceArrayInterfaces {
-		for _, opIntf := range retIA.InstanceArrayOperation.InstanceArrayInterfaces {
-			if opIntf.InstanceArrayInterfaceID == intf.InstanceArrayInterfaceID {
-				copyInstanceArrayInterfaceToOperation(intf, &opIntf)
+		for i := range retIA.InstanceArrayOperation.InstanceArrayInterfaces {
+			opIntf := &retIA.InstanceArrayOperation.InstanceArrayInterfaces[i]
+			if opIntf.InstanceArrayInterfaceIndex == intf.InstanceArrayInterfaceIndex {
+				intf.InstanceArrayInterfaceChangeID = opIntf.InstanceArrayInterfaceChangeID
+				copyInstanceArrayInterfaceToOperation(intf, opIntf)
 			}
 		}
 	}
@@ -466,18 +1507,70 @@ func resourceInstanceArrayUpdate(ctx context.Context, d *schema.ResourceData, me
 	//update the main operation object
 	copyInstanceArrayToOperation(ia, retIA.InstanceArrayOperation)
 
-	bSwapExistingInstancesHardware := false
-	bkeepDetachingDrives := false
+	//only pass the instances to delete when scaling down, they are ignored otherwise
+	var instancesToDelete *[]int
+
+	if o, n := d.GetChange("instance_array_instance_count"); n.(int) < o.(int) {
+		toDelete := d.Get("instances_to_delete").(*schema.Set).List()
+
+		if len(toDelete) > 0 {
+			instanceList, err := client.InstanceArrayInstances(id)
+			if err != nil {
+				return diag.FromErr(err)
+			}
+
+			previous, _ := d.GetChange("instances_to_delete")
+
+			ids, err := expandInstancesToDelete(toDelete, previous.(*schema.Set).List(), *instanceList)
+			if err != nil {
+				return diag.FromErr(err)
+			}
+
+			if len(ids) > 0 {
+				instancesToDelete = &ids
+			}
+		}
+	}
+
+	bSwapExistingInstancesHardware := d.Get("swap_existing_instances_hardware").(bool)
+	bkeepDetachingDrives := d.Get("keep_detaching_drives").(bool)
+
+	if swap := expandStringList(d.Get("hardware_swap_required").([]interface{})); d.HasChange("hardware_swap_required") && len(swap) > 0 {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  instanceArrayHardwareSwapMessage(ia.InstanceArrayLabel, swap, bSwapExistingInstancesHardware),
+		})
+	}
+
+	if reinstall := expandStringList(d.Get("user_data_reinstall_required").([]interface{})); d.HasChange("user_data_reinstall_required") && len(reinstall) > 0 {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  userDataReinstallMessage(ia.InstanceArrayLabel, reinstall),
+		})
+	}
 
-	editedIA, err := client.InstanceArrayEdit(id, *retIA.InstanceArrayOperation, &bSwapExistingInstancesHardware, &bkeepDetachingDrives, nil, nil)
+	editedIA, err := client.InstanceArrayEdit(id, *retIA.InstanceArrayOperation, &bSwapExistingInstancesHardware, &bkeepDetachingDrives, nil, instancesToDelete)
 
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
-	/* custom variables for instances */
 	cvList := d.Get("instance_custom_variables").([]interface{})
-	dg := updateInstancesCustomVariables(cvList, id, client)
+	iList := d.Get("instance_server_type").([]interface{})
+
+	oldMapping, _ := d.GetChange("instance_index_mapping")
+
+	mapping, dg := updateInstanceIndexMapping(cvList, iList, id, oldMapping.(map[string]interface{}), client)
+
+	if dg.HasError() {
+		resourceInstanceArrayRead(ctx, d, meta)
+		return dg
+	}
+
+	d.Set("instance_index_mapping", mapping)
+
+	/* custom variables for instances */
+	dg = updateInstancesCustomVariables(cvList, id, mapping, client)
 
 	if dg.HasError() {
 		resourceInstanceArrayRead(ctx, d, meta)
@@ -487,8 +1580,25 @@ func resourceInstanceArrayUpdate(ctx context.Context, d *schema.ResourceData, me
 	diags = append(diags, dg...)
 
 	/* update server types */
-	iList := d.Get("instance_server_type").([]interface{})
-	dg = updateInstancesServerTypes(iList, id, client)
+	dg = updateInstancesServerTypes(iList, id, mapping, client)
+
+	if dg.HasError() {
+		resourceInstanceArrayRead(ctx, d, meta)
+		return dg
+	}
+
+	diags = append(diags, dg...)
+
+	/* user data */
+	dg = updateUserDataOSAssets(d, id, mapping, client)
+
+	if dg.HasError() {
+		resourceInstanceArrayRead(ctx, d, meta)
+		return dg
+	}
+
+	/* ssh keys */
+	dg = updateSSHKeysOSAsset(d, id, meta)
 
 	if dg.HasError() {
 		resourceInstanceArrayRead(ctx, d, meta)
@@ -530,6 +1640,15 @@ func resourceInstanceArrayUpdate(ctx context.Context, d *schema.ResourceData, me
 		}
 	}
 
+	dg = bootInstancesCustomISO(d, id, meta)
+
+	if dg.HasError() {
+		resourceInstanceArrayRead(ctx, d, meta)
+		return dg
+	}
+
+	diags = append(diags, dg...)
+
 	dg = resourceInstanceArrayRead(ctx, d, meta)
 
 	return diags
@@ -581,6 +1700,19 @@ func resourceInstanceArrayDelete(ctx context.Context, d *schema.ResourceData, me
 		}
 	}
 
+	instanceAssetIDs := map[string]int{}
+	for label, assetID := range d.Get("instance_user_data_os_asset_ids").(map[string]interface{}) {
+		instanceAssetIDs[label] = assetID.(int)
+	}
+
+	if err := deleteInstanceArrayOSAssets(d.Get("volume_template_id").(int), d.Get("user_data_os_asset_id").(int), instanceAssetIDs, client); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := deleteInstanceArrayOSAssets(d.Get("volume_template_id").(int), d.Get("ssh_keys_os_asset_id").(int), nil, client); err != nil {
+		return diag.FromErr(err)
+	}
+
 	d.SetId("")
 	return diags
 }
@@ -598,7 +1730,7 @@ func flattenInstanceArray(d *schema.ResourceData, instanceArray mc.InstanceArray
 	d.Set("instance_array_disk_size_mbytes", instanceArray.InstanceArrayDiskSizeMBytes)
 	d.Set("volume_template_id", instanceArray.VolumeTemplateID)
 	d.Set("instance_array_firewall_managed", instanceArray.InstanceArrayFirewallManaged)
-	d.Set("instance_array_additional_wan_ipv4_json", instanceArray.InstanceArrayAdditionalWanIPv4JSON)
+	flattenAdditionalWanIPv4(d, instanceArray.InstanceArrayAdditionalWanIPv4JSON)
 	d.Set("infrastructure_id", instanceArray.InfrastructureID)
 	d.Set("drive_array_id_boot", instanceArray.DriveArrayIDBoot)
 
@@ -607,20 +1739,23 @@ func flattenInstanceArray(d *schema.ResourceData, instanceArray mc.InstanceArray
 	case []interface{}:
 		d.Set("instance_array_custom_variables", make(map[string]string))
 	default:
-		iacv := make(map[string]string)
+		iacv := make(map[string]interface{})
 
 		for k, v := range instanceArray.InstanceArrayCustomVariables.(map[string]interface{}) {
 			iacv[k] = v.(string)
 		}
-		d.Set("instance_array_custom_variables", iacv)
+
+		d.Set("instance_array_custom_variables", removeReservedCustomVariables(iacv))
 	}
 
 	/* FIREWALL RULES */
-	fwRules := []interface{}{}
-
-	for _, fw := range instanceArray.InstanceArrayFirewallRules {
-		fwRules = append(fwRules, flattenFirewallRule(fw))
+	configuredRules := []interface{}{}
+	if fwRulesSet, ok := d.Get("firewall_rule").(*schema.Set); ok {
+		configuredRules = fwRulesSet.List()
 	}
+
+	fwRules := flattenFirewallRules(instanceArray.InstanceArrayFirewallRules, configuredRules)
+
 	if len(fwRules) > 0 {
 		d.Set("firewall_rule", schema.NewSet(schema.HashResource(resourceFirewallRule()), fwRules))
 	}
@@ -638,7 +1773,10 @@ func flattenInstanceArray(d *schema.ResourceData, instanceArray mc.InstanceArray
 			for _, intf := range instanceArray.InstanceArrayInterfaces {
 				//if we found it, locate the network it's connected to add it to the list
 				if intf.InstanceArrayInterfaceIndex == interfaceIndex && intf.NetworkID != 0 {
-					interfaces = append(interfaces, flattenInstanceArrayInterface(intf))
+					i := flattenInstanceArrayInterface(intf)
+					i["network_label"] = iaInterface["network_label"]
+					i["network_type"] = iaInterface["network_type"]
+					interfaces = append(interfaces, i)
 				}
 			}
 		}
@@ -670,7 +1808,7 @@ func expandInstanceArray(d *schema.ResourceData) mc.InstanceArray {
 	ia.InstanceArrayDiskCount = d.Get("instance_array_disk_count").(int)
 	ia.InstanceArrayDiskSizeMBytes = d.Get("instance_array_disk_size_mbytes").(int)
 	ia.VolumeTemplateID = d.Get("volume_template_id").(int)
-	ia.InstanceArrayAdditionalWanIPv4JSON = d.Get("instance_array_additional_wan_ipv4_json").(string)
+	ia.InstanceArrayAdditionalWanIPv4JSON = expandAdditionalWanIPv4(d)
 	ia.DriveArrayIDBoot = d.Get("drive_array_id_boot").(int)
 	ia.InstanceArrayFirewallManaged = d.Get("instance_array_firewall_managed").(bool)
 
@@ -679,7 +1817,9 @@ func expandInstanceArray(d *schema.ResourceData) mc.InstanceArray {
 		fwRules := []mc.FirewallRule{}
 
 		for _, fwMap := range fwRulesSet.List() {
-			fwRules = append(fwRules, expandFirewallRule(fwMap.(map[string]interface{})))
+			//errors are reported at plan time by resourceInstanceArrayCustomizeDiff
+			fw, _ := expandFirewallRuleMap(fwMap.(map[string]interface{}))
+			fwRules = append(fwRules, fw)
 		}
 
 		ia.InstanceArrayFirewallRules = fwRules
@@ -724,6 +1864,8 @@ func flattenFirewallRule(fw mc.FirewallRule) map[string]interface{} {
 	d["firewall_rule_protocol"] = fw.FirewallRuleProtocol
 	d["firewall_rule_ip_address_type"] = fw.FirewallRuleIPAddressType
 	d["firewall_rule_enabled"] = fw.FirewallRuleEnabled
+	d["source_cidr"] = ""
+	d["destination_cidr"] = ""
 
 	return d
 }
@@ -751,9 +1893,78 @@ func flattenInstanceArrayInterface(i mc.InstanceArrayInterface) map[string]inter
 	d["interface_index"] = i.InstanceArrayInterfaceIndex
 	d["network_id"] = i.NetworkID
 
+	laggIndexes := []interface{}{}
+	for _, l := range i.InstanceArrayInterfaceLAGGIndexes {
+		//numbers are decoded as float64 from the JSON response
+		switch v := l.(type) {
+		case float64:
+			laggIndexes = append(laggIndexes, int(v))
+		case int:
+			laggIndexes = append(laggIndexes, v)
+		}
+	}
+	d["lagg_indexes"] = schema.NewSet(schema.HashInt, laggIndexes)
+
 	return d
 }
 
+//flattenInstances flattens the instances sorted by id, with their interfaces sorted by index
+func flattenInstances(instances []mc.Instance) []interface{} {
+	res := []interface{}{}
+
+	for _, i := range instances {
+		interfaces := make([]mc.InstanceInterface, len(i.InstanceInterfaces))
+		copy(interfaces, i.InstanceInterfaces)
+
+		sort.Slice(interfaces, func(a, b int) bool {
+			return interfaces[a].InstanceInterfaceIndex < interfaces[b].InstanceInterfaceIndex
+		})
+
+		intfs := []interface{}{}
+		for _, intf := range interfaces {
+			intfs = append(intfs, flattenInstanceInterface(intf))
+		}
+
+		res = append(res, map[string]interface{}{
+			"instance_id":                  i.InstanceID,
+			"instance_label":               i.InstanceLabel,
+			"instance_subdomain":           i.InstanceSubdomain,
+			"instance_subdomain_permanent": i.InstanceSubdomainPermanent,
+			"server_id":                    i.ServerID,
+			"interface":                    intfs,
+		})
+	}
+
+	return res
+}
+
+func flattenInstanceInterface(i mc.InstanceInterface) map[string]interface{} {
+	ipv4 := []interface{}{}
+	ipv6 := []interface{}{}
+
+	for _, ip := range i.InstanceInterfaceIPs {
+		d := map[string]interface{}{
+			"ip_address":         ip.IPHumanReadable,
+			"gateway":            ip.SubnetGatewayHumanReadable,
+			"netmask":            ip.SubnetNetmaskHumanReadable,
+			"subnet_destination": ip.SubnetDestination,
+		}
+
+		if ip.IPType == IP_ADDRESS_TYPE_IPV6 {
+			ipv6 = append(ipv6, d)
+		} else {
+			ipv4 = append(ipv4, d)
+		}
+	}
+
+	return map[string]interface{}{
+		"interface_index": i.InstanceInterfaceIndex,
+		"network_id":      i.NetworkID,
+		"ipv4":            ipv4,
+		"ipv6":            ipv6,
+	}
+}
+
 func expandInstanceArrayInterface(d map[string]interface{}) mc.InstanceArrayInterface {
 
 	var i mc.InstanceArrayInterface
@@ -762,6 +1973,10 @@ func expandInstanceArrayInterface(d map[string]interface{}) mc.InstanceArrayInte
 	i.NetworkID = d["network_id"].(int)
 	i.InstanceArrayID = d["instance_array_id"].(int)
 
+	if laggIndexes, ok := d["lagg_indexes"].(*schema.Set); ok {
+		i.InstanceArrayInterfaceLAGGIndexes = laggIndexes.List()
+	}
+
 	return i
 }
 
@@ -782,13 +1997,13 @@ func flattenInstanceArrayNetworkProfile(networkProfiles map[int]int, d *schema.R
 	return profiles
 }
 
-func flattenInstancesCustomVariables(retInstances *map[string]mc.Instance) []interface{} {
-
-	instanceMap := make(map[int]mc.Instance, len(*retInstances))
+//sortInstances returns the instances ordered by id, which is the order used by instance_index
+func sortInstances(retInstances map[string]mc.Instance) []mc.Instance {
+	instanceMap := make(map[int]mc.Instance, len(retInstances))
 	keys := []int{}
 	instances := []mc.Instance{}
 
-	for _, v := range *retInstances {
+	for _, v := range retInstances {
 		instanceMap[v.InstanceID] = v
 		keys = append(keys, v.InstanceID)
 	}
@@ -799,55 +2014,234 @@ func flattenInstancesCustomVariables(retInstances *map[string]mc.Instance) []int
 		instances = append(instances, instanceMap[id])
 	}
 
+	return instances
+}
+
+//instanceAddressedBlocks returns the instance_custom_variables and instance_server_type blocks in a single list
+func instanceAddressedBlocks(cvList []interface{}, iList []interface{}) []interface{} {
+	blocks := make([]interface{}, 0, len(cvList)+len(iList))
+	blocks = append(blocks, cvList...)
+	blocks = append(blocks, iList...)
+
+	return blocks
+}
+
+func hasInstanceIndexAddress(blocks []interface{}) bool {
+	for _, b := range blocks {
+		if b.(map[string]interface{})["instance_index"].(int) >= 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+//validateInstanceAddress checks that a block refers to its instance by exactly one of instance_index, instance_label or instance_id
+func validateInstanceAddress(block map[string]interface{}) error {
+	set := 0
+
+	if block["instance_index"].(int) >= 0 {
+		set++
+	}
+
+	if block["instance_label"].(string) != "" {
+		set++
+	}
+
+	if block["instance_id"].(int) != 0 {
+		set++
+	}
+
+	if set != 1 {
+		return fmt.Errorf("exactly one of instance_index, instance_label or instance_id must be set in the instance_custom_variables and instance_server_type blocks")
+	}
+
+	return nil
+}
+
+//instanceIndexMapping returns the instance id each instance_index refers to. An index keeps the instance it was
+//mapped to previously for as long as that instance exists so that removing other instances does not move it.
+//The other indexes are mapped by position to instances no other index refers to. Instances being deleted are skipped.
+func instanceIndexMapping(blocks []interface{}, instances []mc.Instance, previous map[string]interface{}) map[string]interface{} {
+	mapping := make(map[string]interface{})
+
+	active := []mc.Instance{}
+	ids := make(map[int]bool, len(instances))
+	for _, instance := range instances {
+		if instance.InstanceOperation.InstanceDeployType == DEPLOY_TYPE_DELETE {
+			continue
+		}
+
+		active = append(active, instance)
+		ids[instance.InstanceID] = true
+	}
+
+	indexes := []int{}
+	seen := make(map[int]bool)
+	for _, b := range blocks {
+		index := b.(map[string]interface{})["instance_index"].(int)
+		if index >= 0 && !seen[index] {
+			seen[index] = true
+			indexes = append(indexes, index)
+		}
+	}
+
+	claimed := make(map[int]bool)
+
+	for _, index := range indexes {
+		key := strconv.Itoa(index)
+
+		if id, ok := previous[key]; ok && ids[id.(int)] && !claimed[id.(int)] {
+			mapping[key] = id.(int)
+			claimed[id.(int)] = true
+		}
+	}
+
+	for _, index := range indexes {
+		key := strconv.Itoa(index)
+
+		if _, ok := mapping[key]; ok {
+			continue
+		}
+
+		if index < len(active) && !claimed[active[index].InstanceID] {
+			mapping[key] = active[index].InstanceID
+			claimed[active[index].InstanceID] = true
+		}
+	}
+
+	return mapping
+}
+
+//resolveInstanceAddress returns the instance a instance_custom_variables or instance_server_type block refers to
+func resolveInstanceAddress(block map[string]interface{}, instances []mc.Instance, mapping map[string]interface{}) (*mc.Instance, error) {
+	instanceID := block["instance_id"].(int)
+	instanceLabel := block["instance_label"].(string)
+	instanceIndex := block["instance_index"].(int)
+
+	if instanceIndex >= 0 {
+		id, ok := mapping[strconv.Itoa(instanceIndex)]
+		if !ok {
+			return nil, fmt.Errorf("instance_index %d cannot be mapped to an instance. It is out of bounds or the instance at that position is already addressed by another instance_index, for example because the instance it was mapped to was removed. Use a number between 0 and instance_array_instance_count-1 that is not in use, or use instance_label or instance_id", instanceIndex)
+		}
+		instanceID = id.(int)
+	}
+
+	for i := range instances {
+		if instanceID != 0 && instances[i].InstanceID == instanceID {
+			return &instances[i], nil
+		}
+
+		if instanceLabel != "" && strings.EqualFold(instances[i].InstanceLabel, instanceLabel) {
+			return &instances[i], nil
+		}
+	}
+
+	if instanceLabel != "" {
+		return nil, fmt.Errorf("instance_label %s is not part of this instance array", instanceLabel)
+	}
+
+	return nil, fmt.Errorf("instance_id %d is not part of this instance array", instanceID)
+}
+
+//updateInstanceIndexMapping resolves the instance_index of the given blocks against the current instances of the instance array
+func updateInstanceIndexMapping(cvList []interface{}, iList []interface{}, instanceArrayID int, previous map[string]interface{}, client *mc.Client) (map[string]interface{}, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	blocks := instanceAddressedBlocks(cvList, iList)
+
+	for _, b := range blocks {
+		if err := validateInstanceAddress(b.(map[string]interface{})); err != nil {
+			return nil, diag.FromErr(err)
+		}
+	}
+
+	instanceList, err := client.InstanceArrayInstances(instanceArrayID)
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	return instanceIndexMapping(blocks, sortInstances(*instanceList), previous), diags
+}
+
+//flattenInstancesCustomVariables returns the custom variables of the instances addressed the same way as in the configured blocks.
+//Instances with custom variables that are not configured are appended by instance_label.
+func flattenInstancesCustomVariables(retInstances *map[string]mc.Instance, configured []interface{}, mapping map[string]interface{}) []interface{} {
+
+	instances := sortInstances(*retInstances)
+
+	//state written before instance_index_mapping existed has no mapping for the configured indexes
+	mapping = instanceIndexMapping(configured, instances, mapping)
+
 	customVars := []interface{}{}
+	flattened := make(map[int]bool)
+
+	for _, cIntf := range configured {
+		c := cIntf.(map[string]interface{})
+		i := map[string]interface{}{
+			"instance_index":     c["instance_index"],
+			"instance_label":     c["instance_label"],
+			"instance_id":        c["instance_id"],
+			"custom_variables":   map[string]interface{}{},
+			"user_data":          c["user_data"],
+			"user_data_encoding": c["user_data_encoding"],
+		}
 
-	for index, instance := range instances {
-		i := make(map[string]interface{})
-		cv := make(map[string]interface{})
-		i["instance_index"] = index
-		switch instance.InstanceCustomVariables.(type) {
-		//todo: add nil
-		case []interface{}:
-			cv = make(map[string]interface{})
-		default:
-			for k, v := range instance.InstanceCustomVariables.(map[string]interface{}) {
-				cv[k] = v.(string)
-			}
+		if instance, err := resolveInstanceAddress(c, instances, mapping); err == nil {
+			i["custom_variables"] = removeReservedCustomVariables(flattenInstanceCustomVariables(instance.InstanceCustomVariables))
+			flattened[instance.InstanceID] = true
+		}
+
+		customVars = append(customVars, i)
+	}
+
+	for _, instance := range instances {
+		if flattened[instance.InstanceID] {
+			continue
 		}
-		i["custom_variables"] = cv
+
+		cv := removeReservedCustomVariables(flattenInstanceCustomVariables(instance.InstanceCustomVariables))
+
 		if len(cv) > 0 {
-			customVars = append(customVars, i)
+			customVars = append(customVars, map[string]interface{}{
+				"instance_index":     -1,
+				"instance_label":     instance.InstanceLabel,
+				"instance_id":        0,
+				"custom_variables":   cv,
+				"user_data":          "",
+				"user_data_encoding": USER_DATA_ENCODING_PLAIN,
+			})
 		}
 	}
 
 	return customVars
 }
 
+func flattenInstanceCustomVariables(customVariables interface{}) map[string]interface{} {
+	cv := make(map[string]interface{})
+
+	switch customVariables.(type) {
+	//todo: add nil
+	case map[string]interface{}:
+		for k, v := range customVariables.(map[string]interface{}) {
+			cv[k] = v.(string)
+		}
+	}
+
+	return cv
+}
+
 //* sets the custom variables on the instances object. Used by the Upgrade function
 //TODO: convert tot an actual expand function that doesn't use the client to set them to make it easier to test
-func updateInstancesCustomVariables(cvList []interface{}, instanceArrayID int, client *mc.Client) diag.Diagnostics {
+func updateInstancesCustomVariables(cvList []interface{}, instanceArrayID int, mapping map[string]interface{}, client *mc.Client) diag.Diagnostics {
 
 	var diags diag.Diagnostics
 	instanceList, err := client.InstanceArrayInstances(instanceArrayID)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	//TODO: flatten instances
-	instanceMap := make(map[int]mc.Instance, len(*instanceList))
-	nInstances := len(*instanceList)
-	keys := []int{}
-	instances := []mc.Instance{}
-
-	for _, v := range *instanceList {
-		instanceMap[v.InstanceID] = v
-		keys = append(keys, v.InstanceID)
-	}
-
-	sort.Ints(keys)
 
-	for _, id := range keys {
-		instances = append(instances, instanceMap[id])
-	}
+	instances := sortInstances(*instanceList)
 
 	currentCVLabelList := make(map[string]int, len(*instanceList))
 
@@ -859,20 +2253,18 @@ func updateInstancesCustomVariables(cvList []interface{}, instanceArrayID int, c
 			instance_custom_variables[k] = v.(string)
 		}
 
-		instance_index := icv["instance_index"].(int)
-
-		if instance_index < 0 || instance_index >= nInstances {
+		instance, err := resolveInstanceAddress(icv, instances, mapping)
+		if err != nil {
 			return append(diags, diag.Diagnostic{
 				Severity: diag.Error,
-				Summary:  fmt.Sprintf("instance_index in custom_variables block out of bounds"),
-				Detail:   fmt.Sprintf("Use a number between 0 and %v (instance_array_instance_count-1). ", nInstances-1),
+				Summary:  "Invalid instance in instance_custom_variables block",
+				Detail:   err.Error(),
 			})
 		}
 
-		instance := instances[instance_index]
 		currentCVLabelList[instance.InstanceLabel] = instance.InstanceID
 		instance.InstanceOperation.InstanceCustomVariables = instance_custom_variables
-		_, err := client.InstanceEdit(instance.InstanceID, instance.InstanceOperation)
+		_, err = client.InstanceEdit(instance.InstanceID, instance.InstanceOperation)
 		if err != nil {
 			return diag.FromErr(err)
 		}
@@ -892,49 +2284,33 @@ func updateInstancesCustomVariables(cvList []interface{}, instanceArrayID int, c
 }
 
 //* sets the server types on each of the instances
-func updateInstancesServerTypes(iList []interface{}, instanceArrayID int, client *mc.Client) diag.Diagnostics {
+func updateInstancesServerTypes(iList []interface{}, instanceArrayID int, mapping map[string]interface{}, client *mc.Client) diag.Diagnostics {
 
 	var diags diag.Diagnostics
 	instanceList, err := client.InstanceArrayInstances(instanceArrayID)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	//TODO: flatten instances
-	instanceMap := make(map[int]mc.Instance, len(*instanceList))
-	nInstances := len(*instanceList)
-	keys := []int{}
-	instances := []mc.Instance{}
-
-	for _, v := range *instanceList {
-		instanceMap[v.InstanceID] = v
-		keys = append(keys, v.InstanceID)
-	}
-
-	sort.Ints(keys)
 
-	for _, id := range keys {
-		instances = append(instances, instanceMap[id])
-	}
+	instances := sortInstances(*instanceList)
 
 	for _, iIntf := range iList {
 		imap := iIntf.(map[string]interface{})
 
-		instance_index := imap["instance_index"].(int)
 		server_type_id := imap["server_type_id"].(int)
 
-		if instance_index < 0 || instance_index >= nInstances {
+		instance, err := resolveInstanceAddress(imap, instances, mapping)
+		if err != nil {
 			return append(diags, diag.Diagnostic{
 				Severity: diag.Error,
-				Summary:  fmt.Sprintf("instance_index in instance_server_type block out of bounds"),
-				Detail:   fmt.Sprintf("Use a number between 0 and %v (instance_array_instance_count-1). ", nInstances-1),
+				Summary:  "Invalid instance in instance_server_type block",
+				Detail:   err.Error(),
 			})
 		}
 
-		instance := instances[instance_index]
-
 		instance.InstanceOperation.ServerTypeID = server_type_id
 
-		_, err := client.InstanceEdit(instance.InstanceID, instance.InstanceOperation)
+		_, err = client.InstanceEdit(instance.InstanceID, instance.InstanceOperation)
 		if err != nil {
 			return diag.FromErr(err)
 		}
@@ -944,6 +2320,175 @@ func updateInstancesServerTypes(iList []interface{}, instanceArrayID int, client
 	return diags
 }
 
+//additionalWanIPv4Config is the format of InstanceArrayAdditionalWanIPv4JSON
+type additionalWanIPv4Config struct {
+	Configs []additionalWanIPv4Subnet `json:"configs"`
+}
+
+type additionalWanIPv4Subnet struct {
+	ForcedSubnetPoolID int `json:"forced_subnet_pool_id"`
+	OverrideVlanID     int `json:"override_vlan_id,omitempty"`
+}
+
+//expandAdditionalWanIPv4 returns the JSON sent to the server from either the additional_wan_ipv4 blocks or the deprecated JSON attribute
+func expandAdditionalWanIPv4(d *schema.ResourceData) string {
+	blocks := d.Get("additional_wan_ipv4").([]interface{})
+
+	if len(blocks) == 0 {
+		return d.Get("instance_array_additional_wan_ipv4_json").(string)
+	}
+
+	config := additionalWanIPv4Config{Configs: []additionalWanIPv4Subnet{}}
+
+	for _, bIntf := range blocks {
+		b := bIntf.(map[string]interface{})
+		config.Configs = append(config.Configs, additionalWanIPv4Subnet{
+			ForcedSubnetPoolID: b["forced_subnet_pool_id"].(int),
+			OverrideVlanID:     b["override_vlan_id"].(int),
+		})
+	}
+
+	//cannot fail, the struct only holds ints
+	bytes, _ := json.Marshal(config)
+
+	return string(bytes)
+}
+
+//flattenAdditionalWanIPv4 sets the JSON attribute if it is used in the configuration, the additional_wan_ipv4 blocks otherwise.
+//Values that do not match the typed format are kept in the JSON attribute so that the difference is visible.
+func flattenAdditionalWanIPv4(d *schema.ResourceData, value string) {
+	if d.Get("instance_array_additional_wan_ipv4_json").(string) != "" {
+		d.Set("instance_array_additional_wan_ipv4_json", value)
+		return
+	}
+
+	blocks := []interface{}{}
+
+	if value != "" && value != "null" {
+		var config additionalWanIPv4Config
+
+		decoder := json.NewDecoder(strings.NewReader(value))
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(&config); err != nil {
+			d.Set("instance_array_additional_wan_ipv4_json", value)
+			return
+		}
+
+		for _, c := range config.Configs {
+			blocks = append(blocks, map[string]interface{}{
+				"forced_subnet_pool_id": c.ForcedSubnetPoolID,
+				"override_vlan_id":      c.OverrideVlanID,
+			})
+		}
+	}
+
+	d.Set("additional_wan_ipv4", blocks)
+}
+
+//validateAdditionalWanIPv4JSON fails on invalid JSON and warns about properties that are not part of the known format
+func validateAdditionalWanIPv4JSON(val interface{}, key string) (warns []string, errs []error) {
+	v := val.(string)
+	if v == "" {
+		return
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal([]byte(v), &raw); err != nil {
+		errs = append(errs, fmt.Errorf("%q is not valid JSON: %s", key, err))
+		return
+	}
+
+	var config additionalWanIPv4Config
+
+	decoder := json.NewDecoder(strings.NewReader(v))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&config); err != nil {
+		warns = append(warns, fmt.Sprintf("%q does not match the known format of {\"configs\":[{\"forced_subnet_pool_id\":1,\"override_vlan_id\":100}]}: %s", key, err))
+	}
+
+	return
+}
+
+//suppressEquivalentJSON ignores differences in formatting and key order between two JSON documents
+func suppressEquivalentJSON(k, old, new string, d *schema.ResourceData) bool {
+	var o, n interface{}
+
+	if err := json.Unmarshal([]byte(old), &o); err != nil {
+		return false
+	}
+
+	if err := json.Unmarshal([]byte(new), &n); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(o, n)
+}
+
+//addFirewallRuleSetsRules adds the rules of the firewall rule sets to the rules of the instance array
+func addFirewallRuleSetsRules(d *schema.ResourceData, ia *mc.InstanceArray, client *mc.Client) error {
+	ids := d.Get("firewall_rule_set_ids").([]interface{})
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	setRules, err := firewallRuleSetsRules(ids, client)
+	if err != nil {
+		return err
+	}
+
+	ia.InstanceArrayFirewallRules = append(ia.InstanceArrayFirewallRules, uniqueFirewallRules(setRules)...)
+
+	return nil
+}
+
+//expandInstancesToDelete returns the ids of the instances listed by label or id in instances_to_delete, each once.
+//Instances already being deleted are skipped, as are entries kept from the previous instances_to_delete whose
+//instance no longer exists, so that the list of an earlier scale down does not have to be cleared.
+func expandInstancesToDelete(toDelete []interface{}, previous []interface{}, instances map[string]mc.Instance) ([]int, error) {
+	ids := []int{}
+	found := map[int]bool{}
+
+	kept := map[string]bool{}
+	for _, p := range previous {
+		kept[strings.ToLower(p.(string))] = true
+	}
+
+	for _, tIntf := range toDelete {
+		t := tIntf.(string)
+		var match *mc.Instance
+
+		for _, instance := range instances {
+			if strconv.Itoa(instance.InstanceID) == t || strings.EqualFold(instance.InstanceLabel, t) {
+				i := instance
+				match = &i
+				break
+			}
+		}
+
+		if match == nil {
+			if kept[strings.ToLower(t)] {
+				continue
+			}
+
+			return nil, fmt.Errorf("instances_to_delete: %s is not the label or id of an instance of this instance array", t)
+		}
+
+		if match.InstanceOperation.InstanceDeployType == DEPLOY_TYPE_DELETE || found[match.InstanceID] {
+			continue
+		}
+
+		found[match.InstanceID] = true
+		ids = append(ids, match.InstanceID)
+	}
+
+	sort.Ints(ids)
+
+	return ids, nil
+}
+
 func copyInstanceArrayToOperation(ia mc.InstanceArray, iao *mc.InstanceArrayOperation) {
 
 	iao.InstanceArrayID = ia.InstanceArrayID
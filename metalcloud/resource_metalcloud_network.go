@@ -3,9 +3,11 @@ package metalcloud
 import (
 	"context"
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
@@ -20,6 +22,7 @@ func resourceNetwork() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		CustomizeDiff: resourceNetworkCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			"infrastructure_id": &schema.Schema{
 				Type:     schema.TypeInt,
@@ -64,6 +67,18 @@ func resourceNetwork() *schema.Resource {
 				Default:  nil,
 				Computed: true,
 			},
+			"adopt_existing": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true, //defaults to true for SAN and WAN networks and false for LAN networks
+			},
+			"adopted": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"adopted_network_label": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
 		},
 	}
 }
@@ -82,27 +97,36 @@ func resourceNetworkCreate(ctx context.Context, d *schema.ResourceData, meta int
 
 	n := expandNetwork(d)
 
-	if n.NetworkType == NETWORK_TYPE_SAN || n.NetworkType == NETWORK_TYPE_WAN {
-		networks, err := client.Networks(infrastructure_id)
+	networks, err := client.Networks(infrastructure_id)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 
-		if err != nil {
-			return diag.FromErr(err)
-		}
+	var diags diag.Diagnostics
 
-		for _, network := range *networks {
-			if network.NetworkType == n.NetworkType {
-				id := fmt.Sprintf("%d", network.NetworkID)
-				d.SetId(id)
+	existing := findAdoptableNetwork(*networks, n, adoptExistingNetwork(d.GetRawConfig(), n.NetworkType))
 
-				if network.NetworkLabel != n.NetworkLabel {
-					dg := resourceNetworkUpdate(ctx, d, meta)
+	if existing != nil {
+		d.SetId(fmt.Sprintf("%d", existing.NetworkID))
+		d.Set("network_id", existing.NetworkID)
+		d.Set("adopted", true)
+		d.Set("adopted_network_label", existing.NetworkLabel)
 
-					if dg.HasError() {
-						return dg
-					}
-				}
+		if n.NetworkLabel != "" && !strings.EqualFold(existing.NetworkLabel, n.NetworkLabel) {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  adoptNetworkRenameMessage(*existing, n.NetworkLabel),
+			})
+
+			dg := resourceNetworkUpdate(ctx, d, meta)
+
+			if dg.HasError() {
+				return append(diags, dg...)
 			}
 		}
+	} else if n.NetworkType == NETWORK_TYPE_SAN || n.NetworkType == NETWORK_TYPE_WAN {
+		//SAN and WAN networks are created with the infrastructure and cannot be created separately
+		return diag.Errorf("%s", adoptNetworkError(infrastructure_id, n, findAdoptableNetwork(*networks, n, true)))
 	} else {
 		network, err := client.NetworkCreate(infrastructure_id, n)
 		if err != nil {
@@ -111,9 +135,107 @@ func resourceNetworkCreate(ctx context.Context, d *schema.ResourceData, meta int
 
 		id := fmt.Sprintf("%d", network.NetworkID)
 		d.SetId(id)
+		d.Set("adopted", false)
 	}
 
-	return resourceNetworkRead(ctx, d, meta)
+	return append(diags, resourceNetworkRead(ctx, d, meta)...)
+}
+
+//resourceNetworkCustomizeDiff shows in the plan which existing network will be adopted and its current label,
+//so that renaming a shared network is visible before apply. The SDK does not allow warnings on plan so the rename
+//is also logged and returned as a warning on apply.
+func resourceNetworkCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() != "" || !d.NewValueKnown("infrastructure_id") {
+		return nil
+	}
+
+	client := meta.(*mc.Client)
+
+	infrastructureID := d.Get("infrastructure_id").(int)
+
+	networks, err := client.Networks(infrastructureID)
+	if err != nil {
+		//the infrastructure might be created in the same apply
+		return nil
+	}
+
+	n := mc.Network{
+		NetworkLabel: d.Get("network_label").(string),
+		NetworkType:  d.Get("network_type").(string),
+	}
+
+	existing := findAdoptableNetwork(*networks, n, adoptExistingNetwork(d.GetRawConfig(), n.NetworkType))
+
+	if existing == nil {
+		if n.NetworkType == NETWORK_TYPE_SAN || n.NetworkType == NETWORK_TYPE_WAN {
+			return fmt.Errorf("%s", adoptNetworkError(infrastructureID, n, findAdoptableNetwork(*networks, n, true)))
+		}
+
+		return d.SetNew("adopted", false)
+	}
+
+	if n.NetworkLabel != "" && !strings.EqualFold(existing.NetworkLabel, n.NetworkLabel) {
+		log.Printf("[WARN] %s", adoptNetworkRenameMessage(*existing, n.NetworkLabel))
+	}
+
+	if err := d.SetNew("network_id", existing.NetworkID); err != nil {
+		return err
+	}
+
+	if err := d.SetNew("adopted", true); err != nil {
+		return err
+	}
+
+	return d.SetNew("adopted_network_label", existing.NetworkLabel)
+}
+
+//adoptExistingNetwork returns the configured adopt_existing. When it is not set SAN and WAN networks, which cannot be
+//created, are adopted and LAN networks are not.
+func adoptExistingNetwork(config cty.Value, networkType string) bool {
+	if !config.IsNull() && config.IsKnown() {
+		if v := config.GetAttr("adopt_existing"); v.IsKnown() && !v.IsNull() {
+			return v.True()
+		}
+	}
+
+	return networkType == NETWORK_TYPE_SAN || networkType == NETWORK_TYPE_WAN
+}
+
+//findAdoptableNetwork returns the existing network that a metalcloud_network resource adopts when adopt_existing is set:
+//the SAN or WAN network of the infrastructure or the LAN network with the same label.
+func findAdoptableNetwork(networks map[string]mc.Network, n mc.Network, adoptExisting bool) *mc.Network {
+	if !adoptExisting {
+		return nil
+	}
+
+	for _, network := range networks {
+		if network.NetworkType != n.NetworkType {
+			continue
+		}
+
+		if n.NetworkType == NETWORK_TYPE_SAN || n.NetworkType == NETWORK_TYPE_WAN {
+			return &network
+		}
+
+		if n.NetworkLabel != "" && strings.EqualFold(network.NetworkLabel, n.NetworkLabel) {
+			return &network
+		}
+	}
+
+	return nil
+}
+
+//adoptNetworkError explains why a SAN or WAN network cannot be created. existing is the network that could be adopted, if any.
+func adoptNetworkError(infrastructureID int, n mc.Network, existing *mc.Network) string {
+	if existing != nil {
+		return fmt.Sprintf("Infrastructure #%d already has %s network %s (#%d). %s networks are created together with the infrastructure and cannot be created. Set adopt_existing to true to adopt it.", infrastructureID, n.NetworkType, existing.NetworkLabel, existing.NetworkID, strings.ToUpper(n.NetworkType))
+	}
+
+	return fmt.Sprintf("Infrastructure #%d has no %s network to adopt. %s networks are created together with the infrastructure.", infrastructureID, n.NetworkType, strings.ToUpper(n.NetworkType))
+}
+
+func adoptNetworkRenameMessage(existing mc.Network, label string) string {
+	return fmt.Sprintf("Adopting %s network %s (#%d) renames it to %s. The original label is restored when the resource is destroyed.", existing.NetworkType, existing.NetworkLabel, existing.NetworkID, label)
 }
 
 func resourceNetworkRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -177,6 +299,22 @@ func resourceNetworkDelete(ctx context.Context, d *schema.ResourceData, meta int
 
 	n, err := client.NetworkGet(id)
 
+	//adopted networks are not owned by terraform so they are only detached, restoring their original label
+	if err == nil && d.Get("adopted").(bool) {
+		originalLabel := d.Get("adopted_network_label").(string)
+
+		if originalLabel != "" && !strings.EqualFold(n.NetworkLabel, originalLabel) && n.NetworkOperation != nil {
+			n.NetworkOperation.NetworkLabel = originalLabel
+
+			if _, err := client.NetworkEdit(id, *n.NetworkOperation); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+
+		d.SetId("")
+		return diags
+	}
+
 	//if the network has already been deleted by infrastructure delete we ignore it, else we actually delete because
 	//that might have been the intent. Note that only LAN networks can be deleted.
 	//SAN and WAN are automatically created and deleted
@@ -0,0 +1,212 @@
+package metalcloud
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+//resourceOSAsset manages a file used when installing an OS template, such as a kickstart or a cloud-init file
+func resourceOSAsset() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceOSAssetCreate,
+		ReadContext:   resourceOSAssetRead,
+		UpdateContext: resourceOSAssetUpdate,
+		DeleteContext: resourceOSAssetDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		Schema: map[string]*schema.Schema{
+			"os_asset_filename": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"os_asset_file_mime": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "text/plain",
+			},
+			"os_asset_usage": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			//only the hash of the content is kept in the state
+			"os_asset_content": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"os_asset_source_url"},
+				StateFunc: func(v interface{}) string {
+					return osAssetContentHash(v.(string))
+				},
+			},
+			"os_asset_source_url": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"os_asset_content"},
+			},
+			"os_asset_variable_names_required": &schema.Schema{
+				Type:     schema.TypeList,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Optional: true,
+			},
+			"os_asset_tags": &schema.Schema{
+				Type:     schema.TypeList,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Optional: true,
+			},
+			"os_asset_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"os_asset_contents_sha256_hex": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"os_asset_file_size_bytes": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceOSAssetCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*mc.Client)
+
+	a, err := client.OSAssetCreate(expandOSAsset(d))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("%d", a.OSAssetID))
+
+	return resourceOSAssetRead(ctx, d, meta)
+}
+
+func resourceOSAssetRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	client := meta.(*mc.Client)
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	a, err := client.OSAssetGet(id)
+	if err != nil {
+		//deleted outside terraform, it is created again on the next apply
+		if isNotFoundError(err) {
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(err)
+	}
+
+	flattenOSAsset(d, *a)
+
+	return diags
+}
+
+func resourceOSAssetUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*mc.Client)
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	a := expandOSAsset(d)
+	a.OSAssetID = id
+
+	if _, err := client.OSAssetUpdate(id, a); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceOSAssetRead(ctx, d, meta)
+}
+
+func resourceOSAssetDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	client := meta.(*mc.Client)
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := client.OSAssetDelete(id); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+
+	return diags
+}
+
+func expandOSAsset(d *schema.ResourceData) mc.OSAsset {
+	var a mc.OSAsset
+
+	a.OSAssetFileName = d.Get("os_asset_filename").(string)
+	a.OSAssetFileMime = d.Get("os_asset_file_mime").(string)
+	a.OSAssetUsage = d.Get("os_asset_usage").(string)
+	a.OSAssetSourceURL = d.Get("os_asset_source_url").(string)
+
+	//the state only holds the hash of the content, the content itself is only available when it changes
+	if d.HasChange("os_asset_content") {
+		if content := d.Get("os_asset_content").(string); content != "" {
+			a.OSAssetContentsBase64 = base64.StdEncoding.EncodeToString([]byte(content))
+		}
+	}
+
+	a.OSAssetVariableNamesRequired = expandStringList(d.Get("os_asset_variable_names_required").([]interface{}))
+	a.OSAssetTags = expandStringList(d.Get("os_asset_tags").([]interface{}))
+
+	return a
+}
+
+func flattenOSAsset(d *schema.ResourceData, a mc.OSAsset) {
+	d.Set("os_asset_id", a.OSAssetID)
+	d.Set("os_asset_filename", a.OSAssetFileName)
+	d.Set("os_asset_file_mime", a.OSAssetFileMime)
+	d.Set("os_asset_usage", a.OSAssetUsage)
+	d.Set("os_asset_source_url", a.OSAssetSourceURL)
+	d.Set("os_asset_contents_sha256_hex", a.OSAssetContentsSHA256Hex)
+	d.Set("os_asset_file_size_bytes", a.OSAssetFileSizeBytes)
+
+	//the state holds the hash of the content so that changes made outside terraform and imports show up as a diff.
+	//The content of assets downloaded from a URL is not configured.
+	if a.OSAssetSourceURL == "" && a.OSAssetFileSizeBytes > 0 {
+		d.Set("os_asset_content", strings.ToLower(a.OSAssetContentsSHA256Hex))
+	} else {
+		d.Set("os_asset_content", "")
+	}
+
+	if len(a.OSAssetVariableNamesRequired) > 0 || len(d.Get("os_asset_variable_names_required").([]interface{})) > 0 {
+		d.Set("os_asset_variable_names_required", a.OSAssetVariableNamesRequired)
+	}
+
+	if len(a.OSAssetTags) > 0 || len(d.Get("os_asset_tags").([]interface{})) > 0 {
+		d.Set("os_asset_tags", a.OSAssetTags)
+	}
+}
+
+func osAssetContentHash(content string) string {
+	if content == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(content))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,442 @@
+package metalcloud
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+//resourceOSTemplate manages an OS template, a volume template installed from OS assets instead of copied from a drive
+func resourceOSTemplate() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceOSTemplateCreate,
+		ReadContext:   resourceOSTemplateRead,
+		UpdateContext: resourceOSTemplateUpdate,
+		DeleteContext: resourceOSTemplateDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		Schema: map[string]*schema.Schema{
+			"volume_template_label": &schema.Schema{
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateDiagFunc: validateLabel,
+			},
+			"volume_template_display_name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"volume_template_description": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"volume_template_size_mbytes": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Computed: true,
+			},
+			"volume_template_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"volume_template_boot_methods_supported": &schema.Schema{
+				Type: schema.TypeSet,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+					ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+						v := val.(string)
+						if v != PXE_ISCSI && v != LOCAL_DRIVES {
+							errs = append(errs, fmt.Errorf("%q must be one of '%s', '%s'. Provided value: %s", key, PXE_ISCSI, LOCAL_DRIVES, v))
+						}
+						return
+					},
+				},
+				Set:      schema.HashString,
+				Required: true,
+			},
+			"volume_template_boot_type": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"volume_template_local_disk_supported": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"volume_template_image_build_required": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"volume_template_provision_via_oob": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"volume_template_os_ready_method": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"volume_template_repo_url": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"volume_template_variables_json": &schema.Schema{
+				Type:             schema.TypeString,
+				Optional:         true,
+				DiffSuppressFunc: suppressEquivalentJSON,
+			},
+			"volume_template_tags": &schema.Schema{
+				Type:     schema.TypeList,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Optional: true,
+			},
+			"operating_system_type": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"operating_system_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"operating_system_architecture": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "x86_64",
+			},
+			"os_template_pre_boot_architecture": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"os_template_initial_user": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"os_template_initial_password": &schema.Schema{
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"os_template_initial_ssh_port": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Default:  22,
+			},
+			"os_template_change_password_after_deploy": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"os_template_use_autogenerated_initial_password": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"os_asset_id_bootloader_local_install": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Default:  0,
+			},
+			"os_asset_id_bootloader_os_boot": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Default:  0,
+			},
+			"os_asset": &schema.Schema{
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem:     resourceOSTemplateOSAsset(),
+			},
+			"volume_template_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceOSTemplateOSAsset() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"os_asset_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Required: true,
+			},
+			"path": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"variables_json": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+		},
+	}
+}
+
+func resourceOSTemplateCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*mc.Client)
+
+	t, err := client.OSTemplateCreate(expandOSTemplate(d))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("%d", t.VolumeTemplateID))
+
+	if err := updateOSTemplateOSAssets(t.VolumeTemplateID, d, client); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceOSTemplateRead(ctx, d, meta)
+}
+
+func resourceOSTemplateRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	client := meta.(*mc.Client)
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	t, err := client.OSTemplateGet(id, false)
+	if err != nil {
+		//deleted outside terraform, it is created again on the next apply
+		if isNotFoundError(err) {
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(err)
+	}
+
+	flattenOSTemplate(d, *t)
+
+	assets, err := client.OSTemplateOSAssets(id)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.Set("os_asset", schema.NewSet(schema.HashResource(resourceOSTemplateOSAsset()), flattenOSTemplateOSAssets(*assets)))
+
+	return diags
+}
+
+func resourceOSTemplateUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*mc.Client)
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	t := expandOSTemplate(d)
+	t.VolumeTemplateID = id
+
+	if _, err := client.OSTemplateUpdate(id, t); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := updateOSTemplateOSAssets(id, d, client); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceOSTemplateRead(ctx, d, meta)
+}
+
+func resourceOSTemplateDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	client := meta.(*mc.Client)
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := client.OSTemplateDelete(id); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+
+	return diags
+}
+
+//updateOSTemplateOSAssets adds, removes and updates the associations of the template with its OS assets
+func updateOSTemplateOSAssets(osTemplateID int, d *schema.ResourceData, client *mc.Client) error {
+	if !d.HasChange("os_asset") {
+		return nil
+	}
+
+	retAssets, err := client.OSTemplateOSAssets(osTemplateID)
+	if err != nil {
+		return err
+	}
+
+	//the user data and SSH keys assets are managed by the instance arrays using the template
+	existing := map[int]mc.OSTemplateOSAssetData{}
+	for _, a := range *retAssets {
+		if a.OSAsset != nil && !isInstanceArrayOSAsset(a.OSAsset) {
+			existing[a.OSAsset.OSAssetID] = a
+		}
+	}
+
+	configured := map[int]bool{}
+
+	for _, aIntf := range d.Get("os_asset").(*schema.Set).List() {
+		a := aIntf.(map[string]interface{})
+
+		osAssetID := a["os_asset_id"].(int)
+		path := a["path"].(string)
+		variablesJSON := a["variables_json"].(string)
+
+		configured[osAssetID] = true
+
+		e, ok := existing[osAssetID]
+		if !ok {
+			if err := client.OSTemplateAddOSAsset(osTemplateID, osAssetID, path, variablesJSON); err != nil {
+				return fmt.Errorf("could not add OS asset %d: %s", osAssetID, err)
+			}
+			continue
+		}
+
+		if e.OSAssetFilePath != path {
+			if err := client.OSTemplateUpdateOSAssetPath(osTemplateID, osAssetID, path); err != nil {
+				return fmt.Errorf("could not update the path of OS asset %d: %s", osAssetID, err)
+			}
+		}
+
+		if e.OSTemplateOSAssetVariablesJSON != variablesJSON {
+			if err := client.OSTemplateUpdateOSAssetVariables(osTemplateID, osAssetID, variablesJSON); err != nil {
+				return fmt.Errorf("could not update the variables of OS asset %d: %s", osAssetID, err)
+			}
+		}
+	}
+
+	for osAssetID := range existing {
+		if !configured[osAssetID] {
+			if err := client.OSTemplateRemoveOSAsset(osTemplateID, osAssetID); err != nil {
+				return fmt.Errorf("could not remove OS asset %d: %s", osAssetID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func expandOSTemplate(d *schema.ResourceData) mc.OSTemplate {
+	var t mc.OSTemplate
+
+	t.VolumeTemplateLabel = d.Get("volume_template_label").(string)
+	t.VolumeTemplateDisplayName = d.Get("volume_template_display_name").(string)
+	t.VolumeTemplateDescription = d.Get("volume_template_description").(string)
+	t.VolumeTemplateSizeMBytes = d.Get("volume_template_size_mbytes").(int)
+	t.VolumeTemplateVersion = d.Get("volume_template_version").(string)
+	t.VolumeTemplateBootType = d.Get("volume_template_boot_type").(string)
+	t.VolumeTemplateLocalDiskSupported = d.Get("volume_template_local_disk_supported").(bool)
+	t.VolumeTemplateImageBuildRequired = d.Get("volume_template_image_build_required").(bool)
+	t.VolumeTemplateProvisionViaOOB = d.Get("volume_template_provision_via_oob").(bool)
+	t.VolumeTemplateOSReadyMethod = d.Get("volume_template_os_ready_method").(string)
+	t.VolumeTemplateRepoURL = d.Get("volume_template_repo_url").(string)
+	t.VolumeTemplateVariablesJSON = d.Get("volume_template_variables_json").(string)
+	t.VolumeTemplateTags = expandStringList(d.Get("volume_template_tags").([]interface{}))
+	t.VolumeTemplateIsOSTemplate = true
+
+	t.VolumeTemplateBootMethodsSupported = strings.Join(expandStringList(d.Get("volume_template_boot_methods_supported").(*schema.Set).List()), ",")
+
+	t.VolumeTemplateOperatingSystem = &mc.OperatingSystem{
+		OperatingSystemType:         d.Get("operating_system_type").(string),
+		OperatingSystemVersion:      d.Get("operating_system_version").(string),
+		OperatingSystemArchitecture: d.Get("operating_system_architecture").(string),
+	}
+
+	t.OSTemplatePreBootArchitecture = d.Get("os_template_pre_boot_architecture").(string)
+
+	t.OSTemplateCredentials = &mc.OSTemplateCredentials{
+		OSTemplateInitialUser:                     d.Get("os_template_initial_user").(string),
+		OSTemplateInitialPassword:                 d.Get("os_template_initial_password").(string),
+		OSTemplateInitialSSHPort:                  d.Get("os_template_initial_ssh_port").(int),
+		OSTemplateChangePasswordAfterDeploy:       d.Get("os_template_change_password_after_deploy").(bool),
+		OSTemplateUseAutogeneratedInitialPassword: d.Get("os_template_use_autogenerated_initial_password").(bool),
+	}
+
+	t.OSAssetBootloaderLocalInstall = d.Get("os_asset_id_bootloader_local_install").(int)
+	t.OSAssetBootloaderOSBoot = d.Get("os_asset_id_bootloader_os_boot").(int)
+
+	return t
+}
+
+//flattenOSTemplate sets the template properties, the initial password is never read back
+func flattenOSTemplate(d *schema.ResourceData, t mc.OSTemplate) {
+	d.Set("volume_template_id", t.VolumeTemplateID)
+	d.Set("volume_template_label", t.VolumeTemplateLabel)
+	d.Set("volume_template_display_name", t.VolumeTemplateDisplayName)
+	d.Set("volume_template_description", t.VolumeTemplateDescription)
+	d.Set("volume_template_size_mbytes", t.VolumeTemplateSizeMBytes)
+	d.Set("volume_template_version", t.VolumeTemplateVersion)
+	d.Set("volume_template_boot_type", t.VolumeTemplateBootType)
+	d.Set("volume_template_local_disk_supported", t.VolumeTemplateLocalDiskSupported)
+	d.Set("volume_template_image_build_required", t.VolumeTemplateImageBuildRequired)
+	d.Set("volume_template_provision_via_oob", t.VolumeTemplateProvisionViaOOB)
+	d.Set("volume_template_os_ready_method", t.VolumeTemplateOSReadyMethod)
+	d.Set("volume_template_repo_url", t.VolumeTemplateRepoURL)
+	d.Set("volume_template_variables_json", t.VolumeTemplateVariablesJSON)
+	d.Set("os_template_pre_boot_architecture", t.OSTemplatePreBootArchitecture)
+	d.Set("os_asset_id_bootloader_local_install", t.OSAssetBootloaderLocalInstall)
+	d.Set("os_asset_id_bootloader_os_boot", t.OSAssetBootloaderOSBoot)
+
+	if len(t.VolumeTemplateTags) > 0 || len(d.Get("volume_template_tags").([]interface{})) > 0 {
+		d.Set("volume_template_tags", t.VolumeTemplateTags)
+	}
+
+	bootMethods := []interface{}{}
+	for _, m := range strings.Split(t.VolumeTemplateBootMethodsSupported, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			bootMethods = append(bootMethods, m)
+		}
+	}
+	d.Set("volume_template_boot_methods_supported", schema.NewSet(schema.HashString, bootMethods))
+
+	if t.VolumeTemplateOperatingSystem != nil {
+		d.Set("operating_system_type", t.VolumeTemplateOperatingSystem.OperatingSystemType)
+		d.Set("operating_system_version", t.VolumeTemplateOperatingSystem.OperatingSystemVersion)
+		d.Set("operating_system_architecture", t.VolumeTemplateOperatingSystem.OperatingSystemArchitecture)
+	}
+
+	if t.OSTemplateCredentials != nil {
+		d.Set("os_template_initial_user", t.OSTemplateCredentials.OSTemplateInitialUser)
+		d.Set("os_template_initial_ssh_port", t.OSTemplateCredentials.OSTemplateInitialSSHPort)
+		d.Set("os_template_change_password_after_deploy", t.OSTemplateCredentials.OSTemplateChangePasswordAfterDeploy)
+		d.Set("os_template_use_autogenerated_initial_password", t.OSTemplateCredentials.OSTemplateUseAutogeneratedInitialPassword)
+	}
+}
+
+func flattenOSTemplateOSAssets(assets map[string]mc.OSTemplateOSAssetData) []interface{} {
+	res := []interface{}{}
+
+	for _, a := range assets {
+		if a.OSAsset == nil || isInstanceArrayOSAsset(a.OSAsset) {
+			continue
+		}
+
+		res = append(res, map[string]interface{}{
+			"os_asset_id":    a.OSAsset.OSAssetID,
+			"path":           a.OSAssetFilePath,
+			"variables_json": a.OSTemplateOSAssetVariablesJSON,
+		})
+	}
+
+	return res
+}
@@ -60,6 +60,16 @@ func resourceServerFirmwareUpgradePolicy() *schema.Resource {
 				Optional: true,
 				Elem:     resourceServerFirmwareUpgradePolicyRule(),
 			},
+			"swap_existing_instances_hardware": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"keep_detaching_drives": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
 		},
 	}
 }
@@ -101,6 +111,8 @@ func resourceServerFirmwareUpgradePolicyCreate(ctx context.Context, d *schema.Re
 		policy.InstanceArrayIDList,
 		nil,
 		firmwarePolicy.ServerFirmwareUpgradePolicyID,
+		d.Get("swap_existing_instances_hardware").(bool),
+		d.Get("keep_detaching_drives").(bool),
 		client,
 	)
 
@@ -189,6 +201,8 @@ func resourceServerFirmwareUpgradePolicyUpdate(ctx context.Context, d *schema.Re
 			firmwarePolicy.InstanceArrayIDList,
 			retFirmwarePolicy.InstanceArrayIDList,
 			retFirmwarePolicy.ServerFirmwareUpgradePolicyID,
+			d.Get("swap_existing_instances_hardware").(bool),
+			d.Get("keep_detaching_drives").(bool),
 			client,
 		)
 
@@ -200,7 +214,10 @@ func resourceServerFirmwareUpgradePolicyUpdate(ctx context.Context, d *schema.Re
 	return resourceServerFirmwareUpgradePolicyRead(ctx, d, meta)
 }
 
-func updateServerFirmwarePolicyInstanceArrays(newIDs, oldIDs []int, policyID int, client *mc.Client) diag.Diagnostics {
+//updateServerFirmwarePolicyInstanceArrays adds the policy to the new instance arrays and removes it from the old ones.
+//Editing an instance array also applies its pending changes, so the edits use the same hardware swap and drive detach
+//settings as the instance arrays.
+func updateServerFirmwarePolicyInstanceArrays(newIDs, oldIDs []int, policyID int, swapHardware bool, keepDetachingDrives bool, client *mc.Client) diag.Diagnostics {
 	for _, newID := range newIDs {
 		found := false
 		for _, oldID := range oldIDs {
@@ -218,10 +235,8 @@ func updateServerFirmwarePolicyInstanceArrays(newIDs, oldIDs []int, policyID int
 			policiesList := ia.InstanceArrayFirmwarePolicies
 			policiesList = append(policiesList, policyID)
 			ia.InstanceArrayOperation.InstanceArrayFirmwarePolicies = policiesList
-			detachDrives := true
-			swapHardware := false
 
-			_, err = client.InstanceArrayEdit(ia.InstanceArrayID, *ia.InstanceArrayOperation, &swapHardware, &detachDrives, nil, nil)
+			_, err = client.InstanceArrayEdit(ia.InstanceArrayID, *ia.InstanceArrayOperation, &swapHardware, &keepDetachingDrives, nil, nil)
 
 			if err != nil {
 				return diag.FromErr(err)
@@ -252,9 +267,7 @@ func updateServerFirmwarePolicyInstanceArrays(newIDs, oldIDs []int, policyID int
 			}
 
 			ia.InstanceArrayOperation.InstanceArrayFirmwarePolicies = policiesList
-			detachDrives := true
-			swapHardware := false
-			_, err = client.InstanceArrayEdit(ia.InstanceArrayID, *ia.InstanceArrayOperation, &swapHardware, &detachDrives, nil, nil)
+			_, err = client.InstanceArrayEdit(ia.InstanceArrayID, *ia.InstanceArrayOperation, &swapHardware, &keepDetachingDrives, nil, nil)
 
 			if err != nil {
 				return diag.FromErr(err)
@@ -0,0 +1,212 @@
+package metalcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	"golang.org/x/crypto/ssh"
+)
+
+//resourceSSHKey manages an SSH key of the user. The API has no label for the keys, the label is kept as the comment
+//of the key.
+func resourceSSHKey() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceSSHKeyCreate,
+		ReadContext:   resourceSSHKeyRead,
+		DeleteContext: resourceSSHKeyDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		Schema: map[string]*schema.Schema{
+			"ssh_key_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"ssh_key_label": &schema.Schema{
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateSSHKeyLabel,
+			},
+			"ssh_key_public_key": &schema.Schema{
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				ValidateFunc:     validateSSHPublicKey,
+				DiffSuppressFunc: suppressEquivalentSSHPublicKey,
+			},
+			"ssh_key_type": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"ssh_key_fingerprint": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceSSHKeyCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	api, err := getAPIClient(meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	key, err := parseSSHPublicKey(d.Get("ssh_key_public_key").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var created mc.SSHKey
+
+	if err := api.call(&created, "user_ssh_key_create", api.userID, authorizedKey(key, d.Get("ssh_key_label").(string))); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("%d", created.UserSSHKeyID))
+
+	return resourceSSHKeyRead(ctx, d, meta)
+}
+
+func resourceSSHKeyRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	keys, err := userSSHKeys(meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	k, ok := keys[id]
+	if !ok {
+		//deleted outside terraform, it is created again on the next apply
+		d.SetId("")
+		return diags
+	}
+
+	key, label, _, _, err := ssh.ParseAuthorizedKey([]byte(k.UserSSHKey))
+	if err != nil {
+		return diag.Errorf("could not parse SSH key %d: %s", id, err)
+	}
+
+	d.Set("ssh_key_id", k.UserSSHKeyID)
+	d.Set("ssh_key_label", label)
+	d.Set("ssh_key_public_key", authorizedKey(key, ""))
+	d.Set("ssh_key_type", key.Type())
+	d.Set("ssh_key_fingerprint", ssh.FingerprintSHA256(key))
+
+	return diags
+}
+
+func resourceSSHKeyDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	api, err := getAPIClient(meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var deleted interface{}
+
+	if err := api.call(&deleted, "user_ssh_key_delete", id); err != nil && !isNotFoundError(err) {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+
+	return diags
+}
+
+//userSSHKeys returns the SSH keys of the user by id
+func userSSHKeys(meta interface{}) (map[int]mc.SSHKey, error) {
+	api, err := getAPIClient(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw json.RawMessage
+
+	if err := api.call(&raw, "user_ssh_keys", api.userID); err != nil {
+		return nil, err
+	}
+
+	//the API returns an empty list when the user has no keys and an object otherwise
+	list := []mc.SSHKey{}
+
+	if err := json.Unmarshal(raw, &list); err != nil {
+		byID := map[string]mc.SSHKey{}
+
+		if err := json.Unmarshal(raw, &byID); err != nil {
+			return nil, fmt.Errorf("could not decode the SSH keys: %s", err)
+		}
+
+		for _, k := range byID {
+			list = append(list, k)
+		}
+	}
+
+	keys := make(map[int]mc.SSHKey, len(list))
+	for _, k := range list {
+		keys[k.UserSSHKeyID] = k
+	}
+
+	return keys, nil
+}
+
+//authorizedKey returns the key in the authorized_keys format, followed by the comment when set
+func authorizedKey(key ssh.PublicKey, comment string) string {
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+
+	if comment == "" {
+		return line
+	}
+
+	return line + " " + comment
+}
+
+func validateSSHPublicKey(val interface{}, key string) (warns []string, errs []error) {
+	if _, err := parseSSHPublicKey(val.(string)); err != nil {
+		errs = append(errs, fmt.Errorf("%q: %s", key, err))
+	}
+	return
+}
+
+//validateSSHKeyLabel checks the label, which is kept as the comment of the key
+func validateSSHKeyLabel(val interface{}, key string) (warns []string, errs []error) {
+	v := val.(string)
+	if v == "" || strings.ContainsAny(v, " \t\r\n") {
+		errs = append(errs, fmt.Errorf("%q must not be empty or contain whitespace. Provided value: %s", key, v))
+	}
+	return
+}
+
+//suppressEquivalentSSHPublicKey ignores the comment of the key, which is replaced by the label, and differences in spacing
+func suppressEquivalentSSHPublicKey(k, old, new string, d *schema.ResourceData) bool {
+	oldKey, err := parseSSHPublicKey(old)
+	if err != nil {
+		return false
+	}
+
+	newKey, err := parseSSHPublicKey(new)
+	if err != nil {
+		return false
+	}
+
+	return authorizedKey(oldKey, "") == authorizedKey(newKey, "")
+}
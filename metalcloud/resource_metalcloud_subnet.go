@@ -0,0 +1,248 @@
+package metalcloud
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	mc "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+)
+
+//subnet is the subnet object of the API, which is not part of the SDK
+type subnet struct {
+	SubnetID                  int    `json:"subnet_id,omitempty"`
+	NetworkID                 int    `json:"network_id,omitempty"`
+	InfrastructureID          int    `json:"infrastructure_id,omitempty"`
+	SubnetType                string `json:"subnet_type,omitempty"`
+	SubnetDestination         string `json:"subnet_destination,omitempty"`
+	SubnetPrefixSize          int    `json:"subnet_prefix_size,omitempty"`
+	SubnetPoolID              int    `json:"subnet_pool_id,omitempty"`
+	SubnetAutomaticAllocation bool   `json:"subnet_automatic_allocation"`
+	SubnetNetmaskHuman        string `json:"subnet_netmask_human,omitempty"`
+	SubnetGatewayHuman        string `json:"subnet_gateway_human,omitempty"`
+	SubnetRangeStartHuman     string `json:"subnet_range_start_human,omitempty"`
+	SubnetRangeEndHuman       string `json:"subnet_range_end_human,omitempty"`
+	SubnetServiceStatus       string `json:"subnet_service_status,omitempty"`
+}
+
+//resourceSubnet manages a subnet allocated on a LAN or WAN network
+func resourceSubnet() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceSubnetCreate,
+		ReadContext:   resourceSubnetRead,
+		DeleteContext: resourceSubnetDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		CustomizeDiff: resourceSubnetCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"network_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Required: true,
+				ForceNew: true,
+			},
+			"subnet_type": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				Default:      IP_ADDRESS_TYPE_IPV4,
+				ValidateFunc: validation.StringInSlice([]string{IP_ADDRESS_TYPE_IPV4, IP_ADDRESS_TYPE_IPV6}, false),
+			},
+			"subnet_prefix_size": &schema.Schema{
+				Type:     schema.TypeInt,
+				Required: true,
+				ForceNew: true,
+			},
+			"subnet_pool_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"subnet_automatic_allocation": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				ForceNew: true,
+				Default:  false,
+			},
+			"subnet_id": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"subnet_range_start": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"subnet_range_end": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"subnet_netmask": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"subnet_gateway": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+//resourceSubnetCustomizeDiff checks the prefix size against the subnet type and that the network is not a SAN network
+func resourceSubnetCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.NewValueKnown("subnet_type") && d.NewValueKnown("subnet_prefix_size") {
+		if err := validateSubnetPrefixSize(d.Get("subnet_type").(string), d.Get("subnet_prefix_size").(int)); err != nil {
+			return err
+		}
+	}
+
+	if d.Id() != "" || !d.NewValueKnown("network_id") {
+		return nil
+	}
+
+	client := meta.(*mc.Client)
+
+	n, err := client.NetworkGet(d.Get("network_id").(int))
+	if err != nil {
+		//the network might be created in the same apply
+		return nil
+	}
+
+	return validateSubnetNetwork(*n)
+}
+
+func resourceSubnetCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*mc.Client)
+
+	api, err := getAPIClient(meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	networkID := d.Get("network_id").(int)
+
+	n, err := client.NetworkGet(networkID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := validateSubnetNetwork(*n); err != nil {
+		return diag.FromErr(err)
+	}
+
+	s := subnet{
+		NetworkID:                 networkID,
+		InfrastructureID:          n.InfrastructureID,
+		SubnetType:                d.Get("subnet_type").(string),
+		SubnetDestination:         n.NetworkType,
+		SubnetPrefixSize:          d.Get("subnet_prefix_size").(int),
+		SubnetPoolID:              d.Get("subnet_pool_id").(int),
+		SubnetAutomaticAllocation: d.Get("subnet_automatic_allocation").(bool),
+	}
+
+	var created subnet
+
+	if err := api.call(&created, "subnet_create", networkID, s); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(fmt.Sprintf("%d", created.SubnetID))
+
+	return resourceSubnetRead(ctx, d, meta)
+}
+
+func resourceSubnetRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	api, err := getAPIClient(meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var s subnet
+
+	if err := api.call(&s, "subnet_get", id); err != nil {
+		if isNotFoundError(err) {
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(err)
+	}
+
+	//subnets of deleted networks are kept until the next deploy
+	if s.SubnetServiceStatus == SERVICE_STATUS_DELETED {
+		d.SetId("")
+		return diags
+	}
+
+	d.Set("subnet_id", s.SubnetID)
+	d.Set("network_id", s.NetworkID)
+	d.Set("subnet_type", s.SubnetType)
+	d.Set("subnet_prefix_size", s.SubnetPrefixSize)
+	d.Set("subnet_pool_id", s.SubnetPoolID)
+	d.Set("subnet_automatic_allocation", s.SubnetAutomaticAllocation)
+	d.Set("subnet_range_start", s.SubnetRangeStartHuman)
+	d.Set("subnet_range_end", s.SubnetRangeEndHuman)
+	d.Set("subnet_netmask", s.SubnetNetmaskHuman)
+	d.Set("subnet_gateway", s.SubnetGatewayHuman)
+
+	return diags
+}
+
+func resourceSubnetDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	api, err := getAPIClient(meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	id, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var deleted interface{}
+
+	//the subnet is already gone if its network or infrastructure was deleted
+	if err := api.call(&deleted, "subnet_delete", id); err != nil && !isNotFoundError(err) {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+
+	return diags
+}
+
+//validateSubnetPrefixSize checks that the prefix size fits the address family. The server only allocates prefix sizes
+//that are available in its subnet pools.
+func validateSubnetPrefixSize(subnetType string, prefixSize int) error {
+	maxPrefixSize := 32
+	if subnetType == IP_ADDRESS_TYPE_IPV6 {
+		maxPrefixSize = 128
+	}
+
+	if prefixSize < 1 || prefixSize > maxPrefixSize {
+		return fmt.Errorf("subnet_prefix_size must be between 1 and %d for %s subnets. Provided value: %d", maxPrefixSize, subnetType, prefixSize)
+	}
+
+	return nil
+}
+
+//validateSubnetNetwork fails for SAN networks, whose subnets are managed by the server
+func validateSubnetNetwork(n mc.Network) error {
+	if n.NetworkType != NETWORK_TYPE_LAN && n.NetworkType != NETWORK_TYPE_WAN {
+		return fmt.Errorf("network_id: subnets can only be allocated on %s and %s networks, network %s (#%d) is a %s network", NETWORK_TYPE_LAN, NETWORK_TYPE_WAN, n.NetworkLabel, n.NetworkID, n.NetworkType)
+	}
+
+	return nil
+}
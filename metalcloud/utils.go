@@ -2,10 +2,13 @@ package metalcloud
 
 import (
 	"fmt"
+	"net"
 	"regexp"
+	"strings"
 
 	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
 func validateLabel(v interface{}, path cty.Path) diag.Diagnostics {
@@ -34,3 +37,113 @@ func validateMaxOne(v interface{}, path cty.Path) diag.Diagnostics {
 	return diags
 
 }
+
+func validateCIDR(val interface{}, key string) (warns []string, errs []error) {
+	v := val.(string)
+	if v == "" {
+		return
+	}
+
+	if _, _, err := net.ParseCIDR(v); err != nil {
+		errs = append(errs, fmt.Errorf("%q must be a CIDR block such as 192.168.0.0/24 or 2001:db8::/64, got: %s", key, v))
+	}
+
+	return
+}
+
+func validateNetworkType(val interface{}, key string) (warns []string, errs []error) {
+	v := val.(string)
+	if v != "" && v != NETWORK_TYPE_LAN && v != NETWORK_TYPE_SAN && v != NETWORK_TYPE_WAN {
+		errs = append(errs, fmt.Errorf("%q must be one of %s, %s, %s. Provided value: %s", key, NETWORK_TYPE_LAN, NETWORK_TYPE_SAN, NETWORK_TYPE_WAN, v))
+	}
+
+	return
+}
+
+func expandStringList(l []interface{}) []string {
+	res := []string{}
+
+	for _, v := range l {
+		res = append(res, v.(string))
+	}
+
+	return res
+}
+
+//configuredBlocksKnown returns false if any of the given attributes of the configured blocks is not known yet.
+//Blocks whose attributes depend on other resources are only fully known at apply.
+func configuredBlocksKnown(d *schema.ResourceDiff, key string, attributes ...string) bool {
+	if !configuredListKnown(d, key) {
+		return false
+	}
+
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return true
+	}
+
+	v := config.GetAttr(key)
+	if v.IsNull() {
+		return true
+	}
+
+	for it := v.ElementIterator(); it.Next(); {
+		_, e := it.Element()
+		if e.IsNull() {
+			continue
+		}
+		for _, attribute := range attributes {
+			if !e.Type().HasAttribute(attribute) {
+				continue
+			}
+			if !e.GetAttr(attribute).IsKnown() {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+//configuredListKnown returns false if the configured list or any of its elements is not known yet, such as the id
+//of a resource created in the same plan. NewValueKnown only reports the list itself.
+func configuredListKnown(d *schema.ResourceDiff, key string) bool {
+	if !d.NewValueKnown(key) {
+		return false
+	}
+
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return true
+	}
+
+	v := config.GetAttr(key)
+	if !v.IsKnown() {
+		return false
+	}
+
+	if v.IsNull() {
+		return true
+	}
+
+	for it := v.ElementIterator(); it.Next(); {
+		if _, e := it.Element(); !e.IsKnown() {
+			return false
+		}
+	}
+
+	return true
+}
+
+//isNotFoundError returns true if the API reported that the requested object does not exist
+func isNotFoundError(err error) bool {
+	msg := strings.ToLower(err.Error())
+
+	for _, m := range []string{"not found", "not be found", "could not find", "does not exist"} {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+
+	return false
+}